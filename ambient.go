@@ -0,0 +1,78 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import "context"
+
+// ambientLayer is one call to WithValues.  Layers form a chain back up
+// the call stack through their parents.
+type ambientLayer struct {
+	vals   Values
+	parent *ambientLayer
+}
+
+type ambientKey struct{}
+
+// WithValues returns a copy of ctx carrying vals.  Errors created with
+// the context (NewWithContext, Full, WrapErrorCtx, FullWrap) pick up the
+// values of every layer added up the call stack, attaching them to the
+// path element created with the context.
+func WithValues(ctx context.Context, vals ...Value) context.Context {
+	parent, _ := ctx.Value(ambientKey{}).(*ambientLayer)
+
+	return context.WithValue(ctx, ambientKey{}, &ambientLayer{
+		vals:   append(Values{}, vals...),
+		parent: parent,
+	})
+}
+
+// ContextValues returns the values added to ctx with WithValues, from
+// the outermost layer to the innermost.
+func ContextValues(ctx context.Context) Values {
+	if ctx == nil {
+		return nil
+	}
+
+	layers := []*ambientLayer{}
+	for l, _ := ctx.Value(ambientKey{}).(*ambientLayer); l != nil; l = l.parent {
+		layers = append(layers, l)
+	}
+
+	ret := Values{}
+	for i := len(layers) - 1; i >= 0; i-- {
+		ret = append(ret, layers[i].vals...)
+	}
+
+	return ret
+}
+
+// attachAmbient adds the values of any context layers not already seen
+// by e to its last path element, outermost layer first.
+func (e Error) attachAmbient(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+
+	layers := []*ambientLayer{}
+	for l, _ := ctx.Value(ambientKey{}).(*ambientLayer); l != nil; l = l.parent {
+		if e.ambientSeen[l] {
+			break
+		}
+
+		layers = append(layers, l)
+	}
+
+	if len(layers) == 0 {
+		return
+	}
+
+	if e.ambientSeen == nil {
+		e.ambientSeen = map[*ambientLayer]bool{}
+	}
+
+	pe := &e.path[len(e.path)-1]
+	for i := len(layers) - 1; i >= 0; i-- {
+		e.ambientSeen[layers[i]] = true
+		pe.values = append(pe.values, layers[i].vals...)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func ambientRepo(ctx context.Context) e.Error {
+	ctx = e.WithValues(ctx, e.V{K: "table", I: "users"})
+
+	return e.Full[e.DataError](ctx, "select failed", func() e.Values {
+		return e.Values{e.V{K: "sql", I: "SELECT *"}}
+	})
+}
+
+func ambientService(ctx context.Context) e.Error {
+	ctx = e.WithValues(ctx, e.V{K: "tenant", I: "acme"})
+	if err := ambientRepo(ctx); err != nil {
+		return e.FullWrap[e.DataError](e.WithValues(ctx, e.V{K: "op", I: "load"}), err, "loading user", nil)
+	}
+
+	return nil
+}
+
+func TestAmbientValues(t *testing.T) {
+	t.Parallel()
+	Convey("Verify that context annotations are picked up by errors.", t, func() {
+		ctx := e.WithValues(context.Background(), e.V{K: "request_id", I: "r-1"})
+
+		Convey("check values from every layer are attached once", func() {
+			err := ambientService(ctx)
+			path := err.Path()
+			So(len(path), ShouldEqual, 2)
+			vals := path[0].Values()
+			So(vals, ShouldResemble, e.Values{
+				e.V{K: "request_id", I: "r-1"},
+				e.V{K: "tenant", I: "acme"},
+				e.V{K: "table", I: "users"},
+				e.V{K: "sql", I: "SELECT *"},
+			})
+			So(path[1].Values(), ShouldResemble, e.Values{e.V{K: "op", I: "load"}})
+			tenant, ok := e.Lookup[string](err, "tenant")
+			So(ok, ShouldBeTrue)
+			So(tenant, ShouldEqual, "acme")
+		})
+		Convey("check the context values helper", func() {
+			So(e.ContextValues(e.WithValues(ctx, "plain")), ShouldResemble, e.Values{e.V{K: "request_id", I: "r-1"}, "plain"})
+			So(len(e.ContextValues(context.Background())), ShouldEqual, 0)
+		})
+		Convey("check errors without annotations are unaffected", func() {
+			err := e.NewWithContext[e.DataError](context.Background(), "plain")
+			So(len(err.Path()[0].Values()), ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,251 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets calls through and counts failures.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen refuses calls with a CircuitOpenError.
+	BreakerOpen
+	// BreakerHalfOpen lets a limited number of trial calls through.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig configures a CircuitBreaker.
+type BreakerConfig struct {
+	// Name identifies the breaker in errors and callbacks.
+	Name string
+	// TripClasses are the error classes counted as failures; other
+	// errors (e.g. ValidationError, the caller's fault) are ignored.
+	// Defaults to NetworkError, NetworkTempError, APIError and
+	// TimeoutError.
+	TripClasses []ErrorClass
+	// FailureThreshold failures within Window open the breaker.
+	FailureThreshold int
+	Window           time.Duration
+	// OpenTimeout is how long the breaker stays open before trying
+	// calls again.
+	OpenTimeout time.Duration
+	// HalfOpenCalls is the number of trial calls allowed while half-open;
+	// that many successes close the breaker.
+	HalfOpenCalls int
+	// OnStateChange is called (outside the breaker's lock) on every
+	// state transition.
+	OnStateChange func(name string, from, to BreakerState)
+	// Clock is the time source; the system clock if nil.
+	Clock Clock
+}
+
+// CircuitBreaker stops calling a failing dependency once enough errors
+// of the configured classes have been seen, failing fast with a
+// CircuitOpenError until the dependency has had time to recover.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	cfg       BreakerConfig
+	state     BreakerState
+	failures  []time.Time
+	openedAt  time.Time
+	lastErr   Error
+	trials    int
+	successes int
+}
+
+// NewCircuitBreaker returns a closed breaker.
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if len(cfg.TripClasses) == 0 {
+		cfg.TripClasses = []ErrorClass{NetworkError{}, NetworkTempError{}, APIError{}, TimeoutError{}}
+	}
+
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+
+	if cfg.HalfOpenCalls <= 0 {
+		cfg.HalfOpenCalls = 1
+	}
+
+	cfg.Clock = clockOrSystem(cfg.Clock)
+
+	return &CircuitBreaker{cfg: cfg}
+}
+
+// State returns the current state, moving an open breaker whose timeout
+// has passed to half-open.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	from := b.state
+	to := b.advance()
+	b.mu.Unlock()
+
+	b.notify(from, to)
+
+	return to
+}
+
+// Do calls fn unless the breaker is open, recording the outcome.
+func (b *CircuitBreaker) Do(ctx context.Context, fn func(ctx context.Context) Error) Error {
+	b.mu.Lock()
+	from := b.state
+	state := b.advance()
+
+	if state == BreakerOpen || (state == BreakerHalfOpen && b.trials >= b.cfg.HalfOpenCalls) {
+		lastErr := b.lastErr
+		b.mu.Unlock()
+		b.notify(from, state)
+
+		eData := NewWithVals[CircuitOpenError]("circuit breaker open", func() Values {
+			return Values{V{K: "breaker", I: b.cfg.Name}, V{K: "state", I: state.String()}}
+		})
+		if lastErr != nil {
+			// Each caller gets its own copy, so that wrapping it does
+			// not change the error seen by others.
+			eData.originerror = lastErr.clone()
+		}
+
+		return eData
+	}
+
+	if state == BreakerHalfOpen {
+		b.trials++
+	}
+	b.mu.Unlock()
+	b.notify(from, state)
+
+	var err Error
+
+	returned := false
+
+	// Record the outcome even if fn panics or calls runtime.Goexit,
+	// counting either as a failure, so a half-open trial is never lost.
+	// Only a panic is raised again; a Goexit carries on by itself.
+	defer func() {
+		var rec any
+		if !returned {
+			rec = recover()
+		}
+
+		b.mu.Lock()
+		from := b.state
+		to := b.record(err, !returned)
+		b.mu.Unlock()
+		b.notify(from, to)
+
+		if rec != nil {
+			panic(rec)
+		}
+	}()
+
+	err = fn(ctx)
+	returned = true
+
+	return err
+}
+
+// advance moves an open breaker to half-open once its timeout passes.
+// It must be called with the lock held.
+func (b *CircuitBreaker) advance() BreakerState {
+	if b.state == BreakerOpen && !b.cfg.Clock.Now().Before(b.openedAt.Add(b.cfg.OpenTimeout)) {
+		b.state = BreakerHalfOpen
+		b.trials, b.successes = 0, 0
+	}
+
+	return b.state
+}
+
+// record updates the breaker with the outcome of a call, which failed
+// if it panicked.  It must be called with the lock held.
+func (b *CircuitBreaker) record(err Error, panicked bool) BreakerState {
+	now := b.cfg.Clock.Now()
+
+	if !panicked && (err == nil || !b.trips(err.Class())) {
+		if b.state == BreakerHalfOpen {
+			b.successes++
+			if b.successes >= b.cfg.HalfOpenCalls {
+				b.state = BreakerClosed
+				b.failures = nil
+			}
+		}
+
+		return b.state
+	}
+
+	if err != nil {
+		// Keep a copy: err belongs to the caller, who may go on to
+		// wrap it.
+		b.lastErr = err.clone()
+	}
+
+	if b.state == BreakerHalfOpen {
+		b.open(now)
+
+		return b.state
+	}
+
+	cutoff := now.Add(-b.cfg.Window)
+	kept := b.failures[:0]
+
+	for _, t := range b.failures {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+
+	b.failures = append(kept, now)
+	if len(b.failures) >= b.cfg.FailureThreshold {
+		b.open(now)
+	}
+
+	return b.state
+}
+
+func (b *CircuitBreaker) open(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+	b.failures = nil
+}
+
+func (b *CircuitBreaker) trips(ec ErrorClass) bool {
+	for _, tc := range b.cfg.TripClasses {
+		if tc.Number() == ec.Number() && tc.Area() == ec.Area() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *CircuitBreaker) notify(from, to BreakerState) {
+	if from != to && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(b.cfg.Name, from, to)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+	Convey("Verify the class keyed circuit breaker.", t, func() {
+		clk := newFakeClock()
+		transitions := []string{}
+		b := e.NewCircuitBreaker(e.BreakerConfig{
+			Name:             "billing-api",
+			TripClasses:      []e.ErrorClass{e.NetworkError{}, e.APIError{}},
+			FailureThreshold: 3,
+			Window:           time.Minute,
+			OpenTimeout:      10 * time.Second,
+			Clock:            clk,
+			OnStateChange: func(name string, from, to e.BreakerState) {
+				transitions = append(transitions, fmt.Sprintf("%s:%s->%s", name, from, to))
+			},
+		})
+		calls := 0
+		fail := func(class func(string) e.Error) func(context.Context) e.Error {
+			return func(context.Context) e.Error {
+				calls++
+				return class("call failed")
+			}
+		}
+		apiErr := func(msg string) e.Error { return e.New[e.APIError](msg) }
+		ok := func(context.Context) e.Error { calls++; return nil }
+		ctx := context.Background()
+
+		Convey("check caller errors never trip the breaker", func() {
+			for i := 0; i < 10; i++ {
+				So(b.Do(ctx, fail(validErr)).Class(), ShouldEqual, e.ValidationError{})
+			}
+			So(b.State(), ShouldEqual, e.BreakerClosed)
+		})
+		Convey("check failures outside the window are forgotten", func() {
+			b.Do(ctx, fail(apiErr))
+			b.Do(ctx, fail(apiErr))
+			clk.Advance(2 * time.Minute)
+			b.Do(ctx, fail(apiErr))
+			So(b.State(), ShouldEqual, e.BreakerClosed)
+		})
+		Convey("check the breaker opens, fails fast and recovers", func() {
+			for i := 0; i < 3; i++ {
+				b.Do(ctx, fail(apiErr))
+			}
+			So(b.State(), ShouldEqual, e.BreakerOpen)
+			So(calls, ShouldEqual, 3)
+
+			err := b.Do(ctx, ok)
+			So(calls, ShouldEqual, 3)
+			So(err.Class(), ShouldEqual, e.CircuitOpenError{})
+			var last e.Error
+			So(errors.As(err.Unwrap(), &last), ShouldBeTrue)
+			So(last.Class(), ShouldEqual, e.APIError{})
+			name, found := e.Lookup[string](err, "breaker")
+			So(found, ShouldBeTrue)
+			So(name, ShouldEqual, "billing-api")
+
+			clk.Advance(10 * time.Second)
+			So(b.State(), ShouldEqual, e.BreakerHalfOpen)
+			So(b.Do(ctx, fail(apiErr)).Class(), ShouldEqual, e.APIError{})
+			So(b.State(), ShouldEqual, e.BreakerOpen)
+
+			clk.Advance(10 * time.Second)
+			So(b.Do(ctx, ok), ShouldBeNil)
+			So(b.State(), ShouldEqual, e.BreakerClosed)
+			So(transitions, ShouldResemble, []string{
+				"billing-api:closed->open",
+				"billing-api:open->half-open",
+				"billing-api:half-open->open",
+				"billing-api:open->half-open",
+				"billing-api:half-open->closed",
+			})
+		})
+		Convey("check a panicking trial counts as a failure", func() {
+			for i := 0; i < 3; i++ {
+				b.Do(ctx, fail(apiErr))
+			}
+			clk.Advance(10 * time.Second)
+			So(func() {
+				b.Do(ctx, func(context.Context) e.Error { panic("boom") })
+			}, ShouldPanicWith, "boom")
+			So(b.State(), ShouldEqual, e.BreakerOpen)
+
+			clk.Advance(10 * time.Second)
+			So(b.Do(ctx, ok), ShouldBeNil)
+			So(b.State(), ShouldEqual, e.BreakerClosed)
+		})
+		Convey("check a trial calling Goexit counts as a failure without panicking", func() {
+			for i := 0; i < 3; i++ {
+				b.Do(ctx, fail(apiErr))
+			}
+			clk.Advance(10 * time.Second)
+			done := make(chan any)
+			go func() {
+				defer func() { done <- recover() }()
+				b.Do(ctx, func(context.Context) e.Error { runtime.Goexit(); return nil })
+			}()
+			So(<-done, ShouldBeNil)
+			So(b.State(), ShouldEqual, e.BreakerOpen)
+		})
+		Convey("check callers do not share the last error", func() {
+			var orig e.Error
+			for i := 0; i < 3; i++ {
+				orig = b.Do(ctx, fail(apiErr))
+			}
+			e.Wrap(orig, "wrapped by the caller").AddValue("caller", 1)
+
+			first, second := b.Do(ctx, ok), b.Do(ctx, ok)
+			var last1, last2 e.Error
+			So(errors.As(first.Unwrap(), &last1), ShouldBeTrue)
+			So(errors.As(second.Unwrap(), &last2), ShouldBeTrue)
+			So(last1, ShouldNotPointTo, orig)
+			So(last1, ShouldNotPointTo, last2)
+			So(last1.ID(), ShouldEqual, orig.ID())
+			So(last1.LastMessage(), ShouldEqual, "call failed")
+
+			e.Wrap(last1, "wrapped again")
+			So(last2.LastMessage(), ShouldEqual, "call failed")
+			_, found := e.Lookup[int](second, "caller")
+			So(found, ShouldBeFalse)
+		})
+	})
+}
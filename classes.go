@@ -15,8 +15,14 @@
 
 package e
 
+import "net/http"
+
 const defaultArea = "defaultErrors"
 
+// StatusClientClosedRequest is the non-standard status (from nginx) used
+// for CanceledError: the client went away before the response was sent.
+const StatusClientClosedRequest = 499
+
 // NoError is for zero errors or nil errors.
 type NoError struct{}
 
@@ -44,9 +50,10 @@ func (LogicError) Number() uint32 { return 2 } // nolint
 // NotFoundError is used when what you are looking for is not there.
 type NotFoundError struct{}
 
-func (NotFoundError) What() string   { return "NotFoundError" }
-func (NotFoundError) Area() string   { return defaultArea }
-func (NotFoundError) Number() uint32 { return 3 } // nolint
+func (NotFoundError) What() string    { return "NotFoundError" }
+func (NotFoundError) Area() string    { return defaultArea }
+func (NotFoundError) Number() uint32  { return 3 } // nolint
+func (NotFoundError) HTTPStatus() int { return http.StatusNotFound }
 
 // DataError is used for database errors or inconsistent data.
 type DataError struct{}
@@ -72,16 +79,19 @@ func (FileError) Number() uint32 { return 6 } // nolint
 // NetworkError is for network related errors.
 type NetworkError struct{}
 
-func (NetworkError) What() string   { return "NetworkError" }
-func (NetworkError) Area() string   { return defaultArea }
-func (NetworkError) Number() uint32 { return 7 } // nolint
+func (NetworkError) What() string    { return "NetworkError" }
+func (NetworkError) Area() string    { return defaultArea }
+func (NetworkError) Number() uint32  { return 7 } // nolint
+func (NetworkError) HTTPStatus() int { return http.StatusBadGateway }
 
 // NetworkTempError is for transient network errors that can be recovered from later.
 type NetworkTempError struct{}
 
-func (NetworkTempError) What() string   { return "NetworkTempError" }
-func (NetworkTempError) Area() string   { return defaultArea }
-func (NetworkTempError) Number() uint32 { return 8 } // nolint
+func (NetworkTempError) What() string    { return "NetworkTempError" }
+func (NetworkTempError) Area() string    { return defaultArea }
+func (NetworkTempError) Number() uint32  { return 8 } // nolint
+func (NetworkTempError) HTTPStatus() int { return http.StatusServiceUnavailable }
+func (NetworkTempError) Retryable() bool { return true }
 
 // ExecutionError is for when external execution fails for some reason.
 type ExecutionError struct{}
@@ -93,20 +103,49 @@ func (ExecutionError) Number() uint32 { return 9 } // nolint
 // APIError is for external API errors (not network errors).
 type APIError struct{}
 
-func (APIError) What() string   { return "APIError" }
-func (APIError) Area() string   { return defaultArea }
-func (APIError) Number() uint32 { return 10 } // nolint
+func (APIError) What() string    { return "APIError" }
+func (APIError) Area() string    { return defaultArea }
+func (APIError) Number() uint32  { return 10 } // nolint
+func (APIError) HTTPStatus() int { return http.StatusBadGateway }
 
 // ValidationError is for when validation of data fails.
 type ValidationError struct{}
 
-func (ValidationError) What() string   { return "ValidationError" }
-func (ValidationError) Area() string   { return defaultArea }
-func (ValidationError) Number() uint32 { return 11 } // nolint
+func (ValidationError) What() string    { return "ValidationError" }
+func (ValidationError) Area() string    { return defaultArea }
+func (ValidationError) Number() uint32  { return 11 } // nolint
+func (ValidationError) HTTPStatus() int { return http.StatusBadRequest }
 
 // StateError is for when we have a state violation or the application is in an incomplete state.
 type StateError struct{}
 
-func (StateError) What() string   { return "StateError" }
-func (StateError) Area() string   { return defaultArea }
-func (StateError) Number() uint32 { return 12 } // nolint
+func (StateError) What() string    { return "StateError" }
+func (StateError) Area() string    { return defaultArea }
+func (StateError) Number() uint32  { return 12 } // nolint
+func (StateError) HTTPStatus() int { return http.StatusConflict }
+
+// CanceledError is for work abandoned because its context was
+// cancelled, typically by the client going away.
+type CanceledError struct{}
+
+func (CanceledError) What() string    { return "CanceledError" }
+func (CanceledError) Area() string    { return defaultArea }
+func (CanceledError) Number() uint32  { return 13 } // nolint
+func (CanceledError) HTTPStatus() int { return StatusClientClosedRequest }
+
+// TimeoutError is for work abandoned because its context deadline passed.
+type TimeoutError struct{}
+
+func (TimeoutError) What() string    { return "TimeoutError" }
+func (TimeoutError) Area() string    { return defaultArea }
+func (TimeoutError) Number() uint32  { return 14 } // nolint
+func (TimeoutError) HTTPStatus() int { return http.StatusGatewayTimeout }
+
+// CircuitOpenError is returned by a CircuitBreaker that is refusing
+// calls.  The last error that tripped the breaker is wrapped.
+type CircuitOpenError struct{}
+
+func (CircuitOpenError) What() string    { return "CircuitOpenError" }
+func (CircuitOpenError) Area() string    { return defaultArea }
+func (CircuitOpenError) Number() uint32  { return 15 } // nolint
+func (CircuitOpenError) HTTPStatus() int { return http.StatusServiceUnavailable }
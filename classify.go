@@ -0,0 +1,126 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net"
+	"os/exec"
+	"strconv"
+	"sync"
+)
+
+// ClassifyRule maps a go error to an error class, returning false when
+// the rule does not apply.
+type ClassifyRule func(err error) (ErrorClass, bool)
+
+// ruleIs builds a rule matching errors.Is(err, target).
+func ruleIs[T ErrorClass](target error) ClassifyRule {
+	return func(err error) (ErrorClass, bool) {
+		var ec T
+
+		return ec, errors.Is(err, target)
+	}
+}
+
+// ruleAs builds a rule matching errors.As(err, *E).
+func ruleAs[T ErrorClass, E error]() ClassifyRule {
+	return func(err error) (ErrorClass, bool) {
+		var (
+			ec     T
+			target E
+		)
+
+		return ec, errors.As(err, &target)
+	}
+}
+
+var (
+	classifyMu   sync.RWMutex
+	customRules  []ClassifyRule
+	builtinRules = []ClassifyRule{
+		ruleIs[NotFoundError](fs.ErrNotExist),
+		ruleIs[NotFoundError](sql.ErrNoRows),
+		ruleIs[TimeoutError](context.DeadlineExceeded),
+		ruleIs[CanceledError](context.Canceled),
+		netRule,
+		ruleAs[ExecutionError, *exec.ExitError](),
+		ruleIs[ExecutionError](exec.ErrNotFound),
+		ruleIs[FileError](fs.ErrPermission),
+		ruleIs[FileError](fs.ErrExist),
+		ruleAs[FileError, *fs.PathError](),
+		ruleAs[ValidationError, *json.SyntaxError](),
+		ruleAs[ValidationError, *json.UnmarshalTypeError](),
+		ruleAs[ValidationError, *strconv.NumError](),
+	}
+)
+
+// classifiedRule keeps the class of an Error found in the chain.
+func classifiedRule(err error) (ErrorClass, bool) {
+	for _, eData := range errorChain(err) {
+		if eData.class != nil && eData.class.Number() != (UnknownError{}).Number() {
+			return eData.class, true
+		}
+	}
+
+	return nil, false
+}
+
+// netRule separates transient network errors from permanent ones.
+func netRule(err error) (ErrorClass, bool) {
+	var netErr net.Error
+	if !errors.As(err, &netErr) {
+		return nil, false
+	}
+
+	if netErr.Timeout() {
+		return NetworkTempError{}, true
+	}
+
+	return NetworkError{}, true
+}
+
+// AddClassifyRule adds a rule used by ClassOf, Classify and WrapError.
+// Rules added by the application are consulted, newest first, before
+// the built-in rules.  The class of an Error already in the chain
+// always wins over any rule.
+func AddClassifyRule(rule ClassifyRule) {
+	classifyMu.Lock()
+	defer classifyMu.Unlock()
+
+	customRules = append([]ClassifyRule{rule}, customRules...)
+}
+
+// ClassOf returns the class the classification rules assign to err, or
+// UnknownError if no rule matches.
+func ClassOf(err error) ErrorClass {
+	if err == nil {
+		return NoError{}
+	}
+
+	if ec, ok := classifiedRule(err); ok {
+		return ec
+	}
+
+	classifyMu.RLock()
+	rules := append(append([]ClassifyRule{}, customRules...), builtinRules...)
+	classifyMu.RUnlock()
+
+	for _, rule := range rules {
+		if ec, ok := rule(err); ok {
+			return ec
+		}
+	}
+
+	return UnknownError{}
+}
+
+// Classify wraps a go error with the class chosen by the classification
+// rules.  It is equivalent to WrapError[UnknownError](err).
+func Classify(err error) Error {
+	return WrapError[UnknownError](err)
+}
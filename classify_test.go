@@ -0,0 +1,58 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+var errQuota = errors.New("quota exceeded")
+
+func TestClassify(t *testing.T) {
+	Convey("Verify that go errors are classified automatically.", t, func() {
+		Convey("check the built-in rules", func() {
+			_, statErr := os.Stat("/does/not/exist")
+			So(e.ClassOf(statErr), ShouldEqual, e.NotFoundError{})
+			So(e.ClassOf(&fs.PathError{Op: "open", Path: "x", Err: fs.ErrClosed}), ShouldEqual, e.FileError{})
+			So(e.ClassOf(fmt.Errorf("scan: %w", sql.ErrNoRows)), ShouldEqual, e.NotFoundError{})
+			So(e.ClassOf(timeoutErr{}), ShouldEqual, e.NetworkTempError{})
+			So(e.ClassOf(context.DeadlineExceeded), ShouldEqual, e.TimeoutError{})
+			So(e.ClassOf(context.Canceled), ShouldEqual, e.CanceledError{})
+			So(e.ClassOf(&exec.ExitError{}), ShouldEqual, e.ExecutionError{})
+			So(e.ClassOf(errors.New("mystery")), ShouldEqual, e.UnknownError{})
+			So(e.ClassOf(nil), ShouldEqual, e.NoError{})
+		})
+		Convey("check classify and the WrapError default", func() {
+			err := e.Classify(fmt.Errorf("load: %w", sql.ErrNoRows))
+			So(err.Class(), ShouldEqual, e.NotFoundError{})
+			So(err.Path()[0].FuncName, ShouldEqual, "github.com/paudley/e_test.TestClassify.func1.2")
+			So(errors.Is(err, sql.ErrNoRows), ShouldBeTrue)
+			So(e.WrapError[e.UnknownError](timeoutErr{}).Class(), ShouldEqual, e.NetworkTempError{})
+			So(e.WrapError[e.DataError](timeoutErr{}).Class(), ShouldEqual, e.DataError{})
+			inner := e.New[e.APIError]("bad gateway")
+			So(e.ClassOf(fmt.Errorf("call: %w", inner)), ShouldEqual, e.APIError{})
+		})
+		Convey("check application rules", func() {
+			e.AddClassifyRule(func(err error) (e.ErrorClass, bool) {
+				return e.StateError{}, errors.Is(err, errQuota)
+			})
+			So(e.Classify(errQuota).Class(), ShouldEqual, e.StateError{})
+		})
+	})
+}
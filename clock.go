@@ -0,0 +1,28 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import "time"
+
+// Clock abstracts time for the parts of this package that wait or
+// measure windows (retries, circuit breakers, log deduplication) so
+// they can be tested without sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the real wall clock.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time                         { return time.Now() }
+func (SystemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// clockOrSystem returns clk, or the system clock if it is nil.
+func clockOrSystem(clk Clock) Clock {
+	if clk == nil {
+		return SystemClock{}
+	}
+
+	return clk
+}
@@ -0,0 +1,225 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContextField is a named value extracted from an error's origin context.
+type ContextField struct {
+	Name  string
+	Value any
+}
+
+type contextKey struct {
+	name string
+	key  any
+}
+
+var (
+	contextKeysMu sync.RWMutex
+	contextKeys   []contextKey
+	retainContext bool
+)
+
+// RegisterContextKey registers a context key (as passed to
+// context.WithValue) whose value is copied into the field name when an
+// error is created with a context.  Registering a name again replaces
+// its key.
+func RegisterContextKey(name string, key any) {
+	contextKeysMu.Lock()
+	defer contextKeysMu.Unlock()
+
+	for i := range contextKeys {
+		if contextKeys[i].name == name {
+			contextKeys[i].key = key
+
+			return
+		}
+	}
+
+	contextKeys = append(contextKeys, contextKey{name: name, key: key})
+}
+
+// SetRetainContext controls whether errors keep a reference to their
+// origin context after the registered fields have been extracted.  It
+// is off by default so that errors do not keep everything stored in a
+// request context alive; OriginContext returns nil unless it is set.
+func SetRetainContext(retain bool) {
+	contextKeysMu.Lock()
+	defer contextKeysMu.Unlock()
+
+	retainContext = retain
+}
+
+// extractContext returns the registered fields present in ctx and
+// whether ctx itself should be kept.
+func extractContext(ctx context.Context) ([]ContextField, bool) {
+	contextKeysMu.RLock()
+	defer contextKeysMu.RUnlock()
+
+	fields := []ContextField{}
+
+	for _, ck := range contextKeys {
+		if val := ctx.Value(ck.key); val != nil {
+			fields = append(fields, ContextField{Name: ck.name, Value: val})
+		}
+	}
+
+	return fields, retainContext
+}
+
+// ContextState records the state of the context.Context an error was
+// created with, at the time it was attached.
+type ContextState struct {
+	// Err is ctx.Err(): nil, context.Canceled or context.DeadlineExceeded.
+	Err error
+	// Cause is context.Cause(ctx), which differs from Err when the
+	// context was cancelled with a cause.
+	Cause error
+	// Deadline is the context deadline, if HasDeadline is set.
+	Deadline    time.Time
+	HasDeadline bool
+	// Remaining is the time left until the deadline when the error was
+	// created.  It is negative once the deadline has passed.
+	Remaining time.Duration
+}
+
+func newContextState(ctx context.Context, now time.Time) ContextState {
+	cs := ContextState{
+		Err:   ctx.Err(),
+		Cause: context.Cause(ctx),
+	}
+	cs.Deadline, cs.HasDeadline = ctx.Deadline()
+
+	if cs.HasDeadline {
+		cs.Remaining = cs.Deadline.Sub(now)
+	}
+
+	return cs
+}
+
+// Done reports whether the context had already ended.
+func (cs ContextState) Done() bool {
+	return cs.Err != nil
+}
+
+// String summarizes the context state for output.
+func (cs ContextState) String() string {
+	ret := "active"
+	if cs.Err != nil {
+		ret = cs.Err.Error()
+	}
+
+	if cs.Cause != nil && cs.Cause != cs.Err { // nolint: errorlint
+		ret += fmt.Sprintf(" (cause: %s)", cs.Cause)
+	}
+
+	if cs.HasDeadline {
+		ret += fmt.Sprintf(", deadline %s (remaining %s)", cs.Deadline.Format(time.RFC3339Nano), cs.Remaining)
+	}
+
+	return ret
+}
+
+// JSON returns the context state for structured output.
+func (cs ContextState) JSON() map[string]any {
+	ret := map[string]any{"Done": cs.Done()}
+
+	if cs.Err != nil {
+		ret["Err"] = cs.Err.Error()
+	}
+
+	if cs.Cause != nil {
+		ret["Cause"] = cs.Cause.Error()
+	}
+
+	if cs.HasDeadline {
+		ret["Deadline"] = cs.Deadline.Format(time.RFC3339Nano)
+		ret["Remaining"] = cs.Remaining.String()
+	}
+
+	return ret
+}
+
+// attachContext records ctx as the origin context of e.  An unclassified
+// error created under an ended context takes its class from ctx.Err().
+func (e Error) attachContext(ctx context.Context) {
+	e.originContextP = true
+
+	if ctx == nil {
+		return
+	}
+
+	var retain bool
+
+	e.contextFields, retain = extractContext(ctx)
+	if retain {
+		e.originContext = ctx
+	}
+
+	cs := newContextState(ctx, e.createdAt)
+	e.ctxState = &cs
+
+	if tc, ok := TraceFromContext(ctx); ok {
+		e.trace = &tc
+	}
+
+	if cs.Err != nil && e.class.Number() == (UnknownError{}).Number() {
+		e.class = ClassOf(cs.Err)
+	}
+}
+
+// ContextState returns the state of the origin context when it was
+// attached to the error.
+func (e Error) ContextState() (ContextState, bool) {
+	if e == nil || e.ctxState == nil {
+		return ContextState{}, false
+	}
+
+	return *e.ctxState, true
+}
+
+// ContextFields returns the registered context values extracted from
+// the origin context, in registration order.
+func (e Error) ContextFields() []ContextField {
+	if e == nil {
+		return nil
+	}
+
+	return e.contextFields
+}
+
+// preparedContextFields returns the context fields made safe to render
+// for out, accounting for anything cut off in trunc.  Values are dumped
+// down to Limits.MaxContextDepth.
+func preparedContextFields(out outputKind, fields []ContextField, trunc *Truncation) []ContextField {
+	ret := make([]ContextField, 0, len(fields))
+	depth := CurrentLimits().MaxContextDepth
+
+	for _, f := range fields {
+		val := prepareValue(out, V{K: f.Name, I: f.Value}, depth, trunc)
+		if v, ok := val.(V); ok {
+			ret = append(ret, ContextField{Name: f.Name, Value: v.I})
+		}
+	}
+
+	return ret
+}
+
+// contextFieldsString formats prepared context fields as key=value
+// pairs.
+func contextFieldsString(fields []ContextField) string {
+	parts := make([]string, 0, len(fields))
+
+	for _, f := range fields {
+		parts = append(parts, fmt.Sprintf("%s=%v", f.Name, f.Value))
+	}
+
+	return strings.Join(parts, " ")
+}
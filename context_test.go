@@ -0,0 +1,106 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var errClientGone = errors.New("client went away")
+
+func TestContextState(t *testing.T) {
+	t.Parallel()
+	Convey("Verify that context cancellation is captured.", t, func() {
+		Convey("check client cancellation with a cause", func() {
+			ctx, cancel := context.WithCancelCause(context.Background())
+			cancel(errClientGone)
+			err := e.NewWithContext[e.UnknownError](ctx, "request aborted")
+			So(err.Class(), ShouldEqual, e.CanceledError{})
+			cs, ok := err.ContextState()
+			So(ok, ShouldBeTrue)
+			So(cs.Done(), ShouldBeTrue)
+			So(cs.Err, ShouldEqual, context.Canceled)
+			So(cs.Cause, ShouldEqual, errClientGone)
+			So(cs.HasDeadline, ShouldBeFalse)
+			So(err.SummarizeConsole(), ShouldContainSubstring, "context canceled (cause: client went away)")
+			js := err.JSON()["ContextState"].(map[string]any)
+			So(js["Cause"], ShouldEqual, "client went away")
+		})
+		Convey("check server timeouts", func() {
+			ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+			defer cancel()
+			err := e.WrapErrorCtx[e.UnknownError](ctx, fmt.Errorf("query"))
+			So(err.Class(), ShouldEqual, e.TimeoutError{})
+			cs, _ := err.ContextState()
+			So(cs.HasDeadline, ShouldBeTrue)
+			So(cs.Remaining, ShouldBeLessThan, 0)
+			So(err.JSON()["ContextState"].(map[string]any)["Err"], ShouldEqual, "context deadline exceeded")
+		})
+		Convey("check live contexts keep the requested class", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+			defer cancel()
+			err := e.NewWithContext[e.DataError](ctx, "bad row")
+			So(err.Class(), ShouldEqual, e.DataError{})
+			cs, _ := err.ContextState()
+			So(cs.Done(), ShouldBeFalse)
+			So(cs.Remaining, ShouldBeGreaterThan, 59*time.Minute)
+			_, ok := fBad().ContextState()
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
+
+type ctxKey string
+
+func TestContextFields(t *testing.T) {
+	Convey("Verify that registered context values are extracted.", t, func() {
+		e.RegisterContextKey("request_id", ctxKey("rid"))
+		e.RegisterContextKey("auth_token", ctxKey("tok"))
+		ctx := context.WithValue(context.Background(), ctxKey("rid"), "req-81")
+		ctx = context.WithValue(ctx, ctxKey("tok"), "abc123")
+		ctx = context.WithValue(ctx, ctxKey("other"), "not extracted")
+
+		Convey("check fields are extracted and redacted", func() {
+			err := e.NewWithContext[e.DataError](ctx, "oops")
+			So(err.OriginContext(), ShouldBeNil)
+			So(len(err.ContextFields()), ShouldEqual, 2)
+			So(err.OriginContextString(), ShouldEqual, "request_id=req-81 auth_token=[REDACTED]")
+			sum := err.SummarizeConsole()
+			So(sum, ShouldContainSubstring, "req-81")
+			So(sum, ShouldNotContainSubstring, "abc123")
+			So(sum, ShouldNotContainSubstring, "not extracted")
+			So(err.JSON()["ContextFields"], ShouldResemble, map[string]any{"request_id": "req-81", "auth_token": e.RedactedText})
+			buf, jerr := json.Marshal(err.JSON())
+			So(jerr, ShouldBeNil)
+			var ej e.ErrorJSON
+			So(json.Unmarshal(buf, &ej), ShouldBeNil)
+			So(ej.ContextFields, ShouldResemble, map[string]any{"request_id": "req-81", "auth_token": e.RedactedText})
+		})
+		Convey("check the context can be retained", func() {
+			e.SetRetainContext(true)
+			defer e.SetRetainContext(false)
+			err := e.FullWrap[e.DataError](ctx, fBad(), "wrapped", nil)
+			So(err.OriginContext(), ShouldNotBeNil)
+			So((*err.OriginContext()).Value(ctxKey("other")), ShouldEqual, "not extracted")
+		})
+		Convey("check nested values follow MaxContextDepth", func() {
+			e.RegisterContextKey("tenant", ctxKey("tenant"))
+			tctx := context.WithValue(ctx, ctxKey("tenant"), map[string]any{"org": map[string]any{"name": "acme"}})
+			err := e.NewWithContext[e.DataError](tctx, "nested")
+			So(err.OriginContextString(), ShouldContainSubstring, "acme")
+
+			e.SetLimits(e.Limits{MaxContextDepth: 1})
+			defer e.SetLimits(e.DefaultLimits)
+			So(err.OriginContextString(), ShouldNotContainSubstring, "acme")
+			So(err.OriginContextString(), ShouldContainSubstring, "<max depth 1 reached>")
+		})
+	})
+}
@@ -0,0 +1,199 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DedupFormat selects how a Deduper writes errors.
+type DedupFormat int
+
+const (
+	// DedupConsole writes SummarizeConsole output and plain summaries.
+	DedupConsole DedupFormat = iota
+	// DedupJSON writes one JSON object per line.
+	DedupJSON
+)
+
+// DedupConfig configures a Deduper.
+type DedupConfig struct {
+	W      io.Writer
+	Format DedupFormat
+	// Window is how long repeats of an error are suppressed after it is
+	// written in full.  Defaults to one minute.
+	Window time.Duration
+	// Clock is the time source; the system clock if nil.
+	Clock Clock
+}
+
+type dedupEntry struct {
+	class      string
+	location   string
+	since      time.Time
+	suppressed int
+}
+
+// Deduper writes errors to a log, writing the first occurrence of each
+// fingerprint in full and counting repeats within a window, which are
+// then written as a single summary line.
+type Deduper struct {
+	mu        sync.Mutex
+	cfg       DedupConfig
+	entries   map[string]*dedupEntry
+	lastSweep time.Time
+}
+
+// NewDeduper returns a Deduper writing to cfg.W.
+func NewDeduper(cfg DedupConfig) *Deduper {
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+
+	cfg.Clock = clockOrSystem(cfg.Clock)
+
+	return &Deduper{cfg: cfg, entries: map[string]*dedupEntry{}, lastSweep: cfg.Clock.Now()}
+}
+
+// Log writes err in full unless an identical error was written within
+// the window, in which case it is only counted.  It reports whether err
+// was written.
+func (d *Deduper) Log(err Error) bool {
+	if err == nil {
+		return false
+	}
+
+	fp := err.Fingerprint()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.cfg.Clock.Now()
+	if now.Sub(d.lastSweep) >= d.cfg.Window {
+		d.sweep(now, false)
+	}
+
+	entry, ok := d.entries[fp]
+	if ok && now.Sub(entry.since) < d.cfg.Window {
+		entry.suppressed++
+
+		return false
+	}
+
+	if ok {
+		d.summarize(fp, entry)
+	}
+
+	origin := err.Path()[0]
+	d.entries[fp] = &dedupEntry{
+		class:    err.Class().What(),
+		location: fmt.Sprintf("%s:%d", origin.FileName, origin.LineNumber),
+		since:    now,
+	}
+	d.write(err)
+
+	return true
+}
+
+// Flush writes summaries for every error with suppressed repeats and
+// forgets all errors seen, e.g. at shutdown.
+func (d *Deduper) Flush() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sweep(d.cfg.Clock.Now(), true)
+}
+
+// Run writes summaries for expired windows every window until ctx is
+// done.  Without it summaries are written as errors are logged.
+func (d *Deduper) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.cfg.Clock.After(d.cfg.Window):
+			d.mu.Lock()
+			d.sweep(d.cfg.Clock.Now(), false)
+			d.mu.Unlock()
+		}
+	}
+}
+
+// sweep summarizes and forgets errors whose window has passed (or all of
+// them).  It must be called with the lock held.
+func (d *Deduper) sweep(now time.Time, all bool) {
+	d.lastSweep = now
+
+	fps := make([]string, 0, len(d.entries))
+	for fp := range d.entries {
+		fps = append(fps, fp)
+	}
+
+	sort.Strings(fps)
+
+	for _, fp := range fps {
+		entry := d.entries[fp]
+		if all || now.Sub(entry.since) >= d.cfg.Window {
+			d.summarize(fp, entry)
+			delete(d.entries, fp)
+		}
+	}
+}
+
+func (d *Deduper) summarize(fp string, entry *dedupEntry) {
+	if entry.suppressed == 0 {
+		return
+	}
+
+	if d.cfg.Format == DedupJSON {
+		line, _ := json.Marshal(map[string]any{
+			"Kind":        "errorSuppressed",
+			"Fingerprint": fp,
+			"Class":       entry.class,
+			"Location":    entry.location,
+			"Suppressed":  entry.suppressed,
+			"Since":       entry.since.Format(time.RFC3339Nano),
+		})
+		_, _ = d.cfg.W.Write(append(line, '\n'))
+
+		return
+	}
+
+	_, _ = fmt.Fprintf(d.cfg.W, "suppressed %s identical %s from %s\n", groupDigits(entry.suppressed), entry.class, entry.location)
+}
+
+func (d *Deduper) write(err Error) {
+	if d.cfg.Format == DedupJSON {
+		line, jerr := json.Marshal(err.JSON())
+		if jerr != nil {
+			line, _ = json.Marshal(map[string]any{"Kind": "errorBacktrace", "Message": err.Error()})
+		}
+
+		_, _ = d.cfg.W.Write(append(line, '\n'))
+
+		return
+	}
+
+	_ = err.WriteConsole(d.cfg.W)
+}
+
+// groupDigits formats n with thousands separators.
+func groupDigits(n int) string {
+	str := strconv.Itoa(n)
+	if n < 0 {
+		return "-" + groupDigits(-n)
+	}
+
+	for i := len(str) - 3; i > 0; i -= 3 {
+		str = str[:i] + "," + str[i:]
+	}
+
+	return str
+}
@@ -0,0 +1,78 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func fRepoError(id int) e.Error {
+	return e.New[e.DataError](fmt.Sprintf("row %d locked", id))
+}
+
+func TestDeduper(t *testing.T) {
+	t.Parallel()
+	Convey("Verify deduplicated error logging.", t, func() {
+		clk := newFakeClock()
+		var buf bytes.Buffer
+		d := e.NewDeduper(e.DedupConfig{W: &buf, Window: time.Minute, Clock: clk})
+		origin := fRepoError(0).Path()[0]
+		location := fmt.Sprintf("%s:%d", origin.FileName, origin.LineNumber)
+
+		Convey("check repeats are suppressed and summarized", func() {
+			So(d.Log(fRepoError(1)), ShouldBeTrue)
+			So(buf.String(), ShouldContainSubstring, "row 1 locked")
+			logged := 0
+			for i := 0; i < 4312; i++ {
+				if d.Log(fRepoError(i)) {
+					logged++
+				}
+			}
+			So(logged, ShouldEqual, 0)
+			So(d.Log(fBad()), ShouldBeTrue)
+			So(strings.Count(buf.String(), "!! --Error"), ShouldEqual, 2)
+
+			clk.Advance(time.Minute)
+			buf.Reset()
+			So(d.Log(fRepoError(7)), ShouldBeTrue)
+			out := buf.String()
+			So(out, ShouldStartWith, "suppressed 4,312 identical DataError from "+location+"\n")
+			So(out, ShouldContainSubstring, "row 7 locked")
+			So(out, ShouldNotContainSubstring, "UnknownError")
+		})
+		Convey("check flush summarizes everything", func() {
+			d.Log(fRepoError(1))
+			d.Log(fRepoError(2))
+			d.Log(fBad())
+			buf.Reset()
+			d.Flush()
+			So(buf.String(), ShouldEqual, "suppressed 1 identical DataError from "+location+"\n")
+			buf.Reset()
+			d.Flush()
+			So(buf.String(), ShouldEqual, "")
+			So(d.Log(fRepoError(3)), ShouldBeTrue)
+		})
+		Convey("check json output", func() {
+			dj := e.NewDeduper(e.DedupConfig{W: &buf, Format: e.DedupJSON, Clock: clk})
+			dj.Log(fRepoError(1))
+			dj.Log(fRepoError(2))
+			dj.Flush()
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			So(len(lines), ShouldEqual, 2)
+			var summary map[string]any
+			So(json.Unmarshal([]byte(lines[1]), &summary), ShouldBeNil)
+			So(summary["Kind"], ShouldEqual, "errorSuppressed")
+			So(summary["Suppressed"], ShouldEqual, 1)
+			So(summary["Location"], ShouldEqual, location)
+			So(summary["Fingerprint"], ShouldEqual, fRepoError(5).Fingerprint())
+		})
+	})
+}
@@ -110,6 +110,11 @@ func fFull() e.Error {
 
 const defaultArea = "defaultErrors"
 
+func init() {
+	e.RegisterContextKey("cval", "cval")
+	e.RegisterContextKey("cval2", "cval2")
+}
+
 func TestErrorCreation(t *testing.T) {
 	t.Parallel()
 	Convey("Verify that error creation works.", t, func() {
@@ -254,22 +259,22 @@ func TestErrorCreation(t *testing.T) {
 			// Check for empty context.
 			So(err.OriginContext(), ShouldBeNil)
 		})
-		Convey("make sure that context is kept if provided", func() {
+		Convey("make sure that context fields are kept if provided", func() {
 			err := fContextError()
 			So(err, ShouldNotBeNil)
-			octx := err.OriginContext()
-			So(octx, ShouldNotBeNil)
-			So(fmt.Sprintf("%v", *octx), ShouldEqual, "context.Background.WithValue(type string, val stringvalue1)")
-			So(err.OriginContextString(), ShouldEqual, "context.Background.WithValue(type string, val stringvalue1)")
+			// The context itself is released once its fields are extracted.
+			So(err.OriginContext(), ShouldBeNil)
+			So(err.ContextFields(), ShouldResemble, []e.ContextField{{Name: "cval", Value: "stringvalue1"}})
+			So(err.OriginContextString(), ShouldEqual, "cval=stringvalue1")
+			So(err.SummarizeConsole(), ShouldContainSubstring, "stringvalue1")
+			So(err.JSON()["ContextFields"], ShouldResemble, map[string]any{"cval": "stringvalue1"})
 			err2 := fBad()
 			So(err2.OriginContextString(), ShouldEqual, "")
 		})
 		Convey("check full error generation", func() {
 			err := fFull()
 			So(err, ShouldNotBeNil)
-			octx := err.OriginContext()
-			So(octx, ShouldNotBeNil)
-			So(fmt.Sprintf("%v", *octx), ShouldEqual, "context.Background.WithValue(type string, val stringvalue2)")
+			So(err.OriginContextString(), ShouldEqual, "cval2=stringvalue2")
 			path := err.Path()
 			So(len(path), ShouldEqual, 1)
 			So(path[0].FuncName, ShouldEqual, "github.com/paudley/e_test.fFull")
@@ -0,0 +1,100 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// FingerprintOptions controls what goes into an error fingerprint.
+type FingerprintOptions struct {
+	// IncludeLines adds line numbers, so errors raised from the same
+	// function at different lines group separately.  Off by default so
+	// fingerprints survive code moving within a file.
+	IncludeLines bool
+	// RawMessages uses messages verbatim instead of replacing numbers,
+	// quoted strings, UUIDs and hex values with placeholders.
+	RawMessages bool
+}
+
+var (
+	fingerprintMu   sync.RWMutex
+	fingerprintOpts FingerprintOptions
+
+	// Order matters: the more specific patterns run first.
+	messageNormalizers = []struct {
+		re   *regexp.Regexp
+		repl string
+	}{
+		{regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`), `"?"`},
+		{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), `<uuid>`},
+		{regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b|\b[0-9a-f]{16,}\b`), `<hex>`},
+		{regexp.MustCompile(`\d+(?:\.\d+)?`), `<n>`},
+	}
+)
+
+// SetFingerprintOptions changes how fingerprints are computed.
+func SetFingerprintOptions(opts FingerprintOptions) {
+	fingerprintMu.Lock()
+	defer fingerprintMu.Unlock()
+
+	fingerprintOpts = opts
+}
+
+// NormalizeMessage replaces the variable parts of an error message
+// (numbers, quoted strings, UUIDs and hex values) with placeholders.
+func NormalizeMessage(msg string) string {
+	for _, n := range messageNormalizers {
+		msg = n.re.ReplaceAllString(msg, n.repl)
+	}
+
+	return msg
+}
+
+// Fingerprint returns a stable identifier for errors that are "the
+// same": the same class raised and wrapped through the same functions
+// with the same message templates.  The format given to Newf or Wrapf is
+// used as is; other messages are normalized (see NormalizeMessage).
+func (e Error) Fingerprint() string {
+	fingerprintMu.RLock()
+	opts := fingerprintOpts
+	fingerprintMu.RUnlock()
+
+	return e.FingerprintWith(opts)
+}
+
+// FingerprintWith is Fingerprint with explicit options.
+func (e Error) FingerprintWith(opts FingerprintOptions) string {
+	if e == nil {
+		return ""
+	}
+
+	h := sha256.New()
+	ec := e.Class()
+	fmt.Fprintf(h, "%s/%s/%d\n", ec.Area(), ec.What(), ec.Number())
+
+	for _, pe := range e.path {
+		msg := pe.Msg
+
+		switch {
+		case pe.Template != "":
+			msg = pe.Template
+		case !opts.RawMessages:
+			msg = NormalizeMessage(msg)
+		}
+
+		fmt.Fprintf(h, "%s|%s|%s", pe.FileName, pe.FuncName, msg)
+
+		if opts.IncludeLines {
+			fmt.Fprintf(h, "|%d", pe.LineNumber)
+		}
+
+		h.Write([]byte{'\n'})
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
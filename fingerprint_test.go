@@ -0,0 +1,78 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fShifted raises the "same" error from two different lines of the same
+// function, standing in for code that has moved between builds.
+func fShifted(moved bool, id int) e.Error {
+	if moved {
+
+		// Blank lines above push this error further down the file.
+		return e.New[e.DataError](fmt.Sprintf("row %d not found in \"users\"", id))
+	}
+
+	return e.New[e.DataError](fmt.Sprintf("row %d not found in \"accounts\"", id))
+}
+
+func fShiftedWrapped(moved bool, id int) e.Error {
+	return e.Wrap(fShifted(moved, id), "loading profile")
+}
+
+func TestFingerprint(t *testing.T) {
+	Convey("Verify that error fingerprints are stable.", t, func() {
+		Convey("check fingerprints survive moved code and variable data", func() {
+			a := fShiftedWrapped(false, 17)
+			b := fShiftedWrapped(true, 9001)
+			So(a.Path()[0].LineNumber, ShouldNotEqual, b.Path()[0].LineNumber)
+			So(a.Fingerprint(), ShouldEqual, b.Fingerprint())
+			So(len(a.Fingerprint()), ShouldEqual, 32)
+			So(a.JSON()["Fingerprint"], ShouldEqual, a.Fingerprint())
+		})
+		Convey("check the fingerprint survives decoding", func() {
+			ctx, cancel := context.WithCancel(e.ContextWithTrace(context.Background(), e.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Flags: 1}))
+			cancel()
+			err := e.WrapErrorCtx[e.DataError](ctx, errors.New("lost"))
+			buf, jerr := json.Marshal(err.JSON())
+			So(jerr, ShouldBeNil)
+			var ej e.ErrorJSON
+			So(json.Unmarshal(buf, &ej), ShouldBeNil)
+			So(ej.Kind, ShouldEqual, "errorBacktrace")
+			So(ej.Fingerprint, ShouldEqual, err.Fingerprint())
+			So(ej.ContextState, ShouldNotBeNil)
+			So(ej.ContextState.Done, ShouldBeTrue)
+			So(ej.ContextState.Err, ShouldEqual, "context canceled")
+			So(ej.Trace, ShouldNotBeNil)
+			So(ej.Trace.TraceID, ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+			So(ej.Trace.Sampled, ShouldBeTrue)
+		})
+		Convey("check different errors have different fingerprints", func() {
+			So(fBad().Fingerprint(), ShouldNotEqual, fBadNested().Fingerprint())
+			So(fShifted(false, 1).Fingerprint(), ShouldNotEqual, fShiftedWrapped(false, 1).Fingerprint())
+			So(e.New[e.DataError]("x").Fingerprint(), ShouldNotEqual, e.New[e.LogicError]("x").Fingerprint())
+			var nilErr e.Error
+			So(nilErr.Fingerprint(), ShouldEqual, "")
+		})
+		Convey("check the options", func() {
+			a, b := fShifted(false, 1), fShifted(true, 1)
+			So(a.FingerprintWith(e.FingerprintOptions{IncludeLines: true}), ShouldNotEqual, b.FingerprintWith(e.FingerprintOptions{IncludeLines: true}))
+			c := fShifted(false, 2)
+			So(a.FingerprintWith(e.FingerprintOptions{RawMessages: true}), ShouldNotEqual, c.FingerprintWith(e.FingerprintOptions{RawMessages: true}))
+		})
+		Convey("check message normalization", func() {
+			So(e.NormalizeMessage(`user 42 "bob" id 123e4567-e89b-12d3-a456-426614174000 at 0xc000123 took 1.5s`),
+				ShouldEqual, `user <n> "?" id <uuid> at <hex> took <n>s`)
+			So(e.NormalizeMessage(`sha deadbeefdeadbeefdeadbeef mismatch`), ShouldEqual, `sha <hex> mismatch`)
+		})
+	})
+}
@@ -0,0 +1,96 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func fNewf(user string, id int) e.Error {
+	return e.Newf[e.DataError]("user %q has no account %d", user, id)
+}
+
+func fWrapf(err e.Error, n int) e.Error {
+	return e.Wrapf(err, "while loading %d accounts", n)
+}
+
+func TestFormatted(t *testing.T) {
+	Convey("Verify formatted constructors.", t, func() {
+		Convey("check the template and args are kept", func() {
+			err := fNewf("bob", 42)
+			So(err.Error(), ShouldEqual, `user "bob" has no account 42`)
+			pe := err.Path()[0]
+			So(pe.FuncName, ShouldEqual, "github.com/paudley/e_test.fNewf")
+			So(pe.Template, ShouldEqual, "user %q has no account %d")
+			So(pe.Args(), ShouldResemble, []any{"bob", 42})
+			So(pe.Values(), ShouldResemble, e.Values{e.V{"arg1", "bob"}, e.V{"arg2", 42}})
+			n, ok := e.Lookup[int](err, "arg2")
+			So(ok, ShouldBeTrue)
+			So(n, ShouldEqual, 42)
+		})
+		Convey("check Wrapf adds a formatted element", func() {
+			err := fWrapf(fNewf("bob", 42), 3)
+			So(err.Error(), ShouldEqual, `user "bob" has no account 42; while loading 3 accounts`)
+			pe := err.Path()[1]
+			So(pe.FuncName, ShouldEqual, "github.com/paudley/e_test.fWrapf")
+			So(pe.Template, ShouldEqual, "while loading %d accounts")
+			So(err.JSON()["Path"].([]e.ErrorPathJSON)[1].Template, ShouldEqual, "while loading %d accounts")
+
+			nilWrap := e.Wrapf(nil, "nothing %s", "here")
+			So(nilWrap.Error(), ShouldEqual, "nothing here")
+			So(nilWrap.Class(), ShouldEqual, e.UnknownError{})
+		})
+		Convey("check %w wraps and classifies the origin error", func() {
+			err := e.Newf[e.UnknownError]("reading %s: %w", "cfg.yaml", fs.ErrNotExist)
+			So(err.Error(), ShouldEqual, "reading cfg.yaml: file does not exist")
+			So(errors.Is(err, fs.ErrNotExist), ShouldBeTrue)
+			So(err.Class(), ShouldEqual, e.NotFoundError{})
+
+			wrapped := e.Wrapf(e.New[e.DataError]("short read"), "decoding header: %w", io.EOF)
+			So(wrapped.LastMessage(), ShouldEqual, "decoding header: EOF")
+			So(errors.Is(wrapped, io.EOF), ShouldBeTrue)
+			So(wrapped.Class(), ShouldEqual, e.DataError{})
+			So(errors.Is(e.Wrapf(nil, "no input: %w", io.EOF), io.EOF), ShouldBeTrue)
+
+			origin := e.WrapError[e.DataError](fs.ErrClosed)
+			joined := e.Wrapf(origin, "then: %w", io.EOF)
+			So(errors.Is(joined, fs.ErrClosed), ShouldBeTrue)
+			So(errors.Is(joined, io.EOF), ShouldBeTrue)
+			So(joined.Class(), ShouldEqual, e.DataError{})
+		})
+		Convey("check every %w error is kept", func() {
+			err := e.Newf[e.UnknownError]("a %w b %w", io.EOF, fs.ErrClosed)
+			So(err.Error(), ShouldEqual, "a EOF b file already closed")
+			So(errors.Is(err, io.EOF), ShouldBeTrue)
+			So(errors.Is(err, fs.ErrClosed), ShouldBeTrue)
+
+			wrapped := e.Wrapf(e.New[e.DataError]("short read"), "%w then %w", io.EOF, fs.ErrClosed)
+			So(errors.Is(wrapped, io.EOF), ShouldBeTrue)
+			So(errors.Is(wrapped, fs.ErrClosed), ShouldBeTrue)
+		})
+		Convey("check the template is used for fingerprints", func() {
+			So(fNewf("bob", 42).Fingerprint(), ShouldEqual, fNewf("alice", 7).Fingerprint())
+			So(fWrapf(fNewf("bob", 1), 2).Fingerprint(), ShouldEqual, fWrapf(fNewf("carol", 3), 4).Fingerprint())
+		})
+		Convey("check the template is used for localization", func() {
+			cat := e.NewCatalog("en")
+			cat.Add("fr", "user %q has no account %d", "le compte {arg2} de {arg1} est introuvable")
+			e.SetCatalog(cat)
+			defer e.SetCatalog(nil)
+			So(fNewf("bob", 42).Localize("fr"), ShouldEqual, "le compte 42 de bob est introuvable")
+			So(fNewf("bob", 42).Localize("en"), ShouldEqual, e.DefaultPublicMessage)
+
+			cat.Add("fr", "token %v rejected", "jeton {arg1} refusé")
+			So(e.Newf[e.ValidationError]("token %v rejected", e.NewSecret("tok-1")).Localize("fr"), ShouldEqual, "jeton "+e.RedactedText+" refusé")
+			cat.AddPlural("fr", "%d rows failed", "arg1", map[string]string{"one": "{arg1} ligne en échec", "other": "{arg1} lignes en échec"})
+			So(e.Newf[e.DataError]("%d rows failed", 1).Localize("fr"), ShouldEqual, "1 ligne en échec")
+			So(e.Newf[e.DataError]("%d rows failed", 5).Localize("fr"), ShouldEqual, "5 lignes en échec")
+		})
+	})
+}
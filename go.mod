@@ -3,15 +3,16 @@ module github.com/paudley/e
 go 1.20
 
 require (
+	github.com/fatih/color v1.17.0
 	github.com/paudley/colorout v1.0.4
 	github.com/smartystreets/goconvey v1.8.1
+	golang.org/x/term v0.19.0
 )
 
 require (
 	github.com/alecthomas/chroma v0.10.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/dlclark/regexp2 v1.11.0 // indirect
-	github.com/fatih/color v1.17.0 // indirect
 	github.com/gopherjs/gopherjs v1.17.2 // indirect
 	github.com/jtolds/gls v4.20.0+incompatible // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
@@ -0,0 +1,125 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// HookEvent identifies what happened to an error when a hook is called.
+type HookEvent int
+
+const (
+	// HookCreate is an error created by one of the New*, Full or Wrap*
+	// constructors.
+	HookCreate HookEvent = iota
+	// HookWrap is a path element added to an existing error.
+	HookWrap
+	// HookSetClass is an error's class changed by SetClass.
+	HookSetClass
+	// HookReport is an error passed to a Reporter.
+	HookReport
+)
+
+func (h HookEvent) String() string {
+	switch h {
+	case HookCreate:
+		return "create"
+	case HookWrap:
+		return "wrap"
+	case HookSetClass:
+		return "setclass"
+	case HookReport:
+		return "report"
+	default:
+		return "unknown"
+	}
+}
+
+// Hook is called with the error and its newest path element: the origin
+// element on creation, the element just added on wrap.  Hooks run
+// synchronously on the caller's goroutine and should be quick.
+type Hook func(event HookEvent, err Error, pe PathElement)
+
+type hookEntry struct {
+	id   uint64
+	hook Hook
+}
+
+var (
+	hooksMu  sync.Mutex
+	hookSeq  uint64
+	hookList atomic.Pointer[[]hookEntry]
+)
+
+// AddHook registers a hook for every error event and returns a function
+// that removes it.  It is safe to call concurrently with errors being
+// created; with no hooks registered the cost is a single atomic load.
+func AddHook(hook Hook) (remove func()) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	hookSeq++
+	id := hookSeq
+
+	hooks := []hookEntry{}
+	if cur := hookList.Load(); cur != nil {
+		hooks = append(hooks, *cur...)
+	}
+
+	hooks = append(hooks, hookEntry{id: id, hook: hook})
+	hookList.Store(&hooks)
+
+	return func() { removeHook(id) }
+}
+
+func removeHook(id uint64) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	cur := hookList.Load()
+	if cur == nil {
+		return
+	}
+
+	hooks := make([]hookEntry, 0, len(*cur))
+
+	for _, h := range *cur {
+		if h.id != id {
+			hooks = append(hooks, h)
+		}
+	}
+
+	hookList.Store(&hooks)
+}
+
+func runHooks(event HookEvent, err Error, pe PathElement) {
+	hooks := hookList.Load()
+	if hooks == nil || len(*hooks) == 0 {
+		return
+	}
+
+	for _, h := range *hooks {
+		h.hook(event, err, pe)
+	}
+}
+
+// created is called by the public constructors once a new error is
+// complete.
+func (e Error) created() {
+	if hooks := hookList.Load(); hooks == nil || len(*hooks) == 0 {
+		return
+	}
+
+	runHooks(HookCreate, e, e.path[0])
+}
+
+// wrapped is called once a path element has been added to e.
+func (e Error) wrapped() {
+	if hooks := hookList.Load(); hooks == nil || len(*hooks) == 0 {
+		return
+	}
+
+	runHooks(HookWrap, e, e.path[len(e.path)-1])
+}
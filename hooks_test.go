@@ -0,0 +1,81 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type hookCall struct {
+	event e.HookEvent
+	msg   string
+	fn    string
+}
+
+func fHookWrap(err e.Error) e.Error {
+	return e.Wrap(err, "hook wrap")
+}
+
+func TestHooks(t *testing.T) {
+	Convey("Verify creation and wrap hooks.", t, func() {
+		var (
+			mu    sync.Mutex
+			calls []hookCall
+		)
+		remove := e.AddHook(func(event e.HookEvent, err e.Error, pe e.PathElement) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, hookCall{event, pe.Msg, pe.FuncName})
+		})
+		defer remove()
+
+		Convey("check create and wrap events carry the new element", func() {
+			err := e.New[e.UnknownError]("hook origin")
+			fHookWrap(err)
+			e.SetClass[e.DataError](err)
+			e.WrapWithVals[e.DataError](err, "with vals", nil)
+			e.FullWrap[e.DataError](context.Background(), nil, "full", nil)
+			So(calls, ShouldHaveLength, 5)
+			So(calls[0], ShouldResemble, hookCall{e.HookCreate, "hook origin", "github.com/paudley/e_test.TestHooks.func1.2"})
+			So(calls[1], ShouldResemble, hookCall{e.HookWrap, "hook wrap", "github.com/paudley/e_test.fHookWrap"})
+			So(calls[2].event, ShouldEqual, e.HookSetClass)
+			So(calls[3].event, ShouldEqual, e.HookWrap)
+			So(calls[3].msg, ShouldEqual, "with vals")
+			So(calls[4].event, ShouldEqual, e.HookCreate)
+			So(calls[4].msg, ShouldEqual, "full")
+		})
+		Convey("check WrapErrorMsg announces creation then the message", func() {
+			e.WrapErrorMsg[e.DataError](context.Canceled, "while waiting")
+			So(calls, ShouldHaveLength, 2)
+			So(calls[0].event, ShouldEqual, e.HookCreate)
+			So(calls[1], ShouldResemble, hookCall{e.HookWrap, "while waiting", "github.com/paudley/e_test.TestHooks.func1.3"})
+		})
+		Convey("check removed hooks are not called", func() {
+			remove()
+			e.New[e.DataError]("unhooked")
+			So(calls, ShouldBeEmpty)
+		})
+		Convey("check hooks can be added while errors are created", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					e.AddHook(func(e.HookEvent, e.Error, e.PathElement) {})()
+				}()
+				go func() {
+					defer wg.Done()
+					e.New[e.DataError]("concurrent")
+				}()
+			}
+			wg.Wait()
+			So(calls, ShouldHaveLength, 8)
+			So(e.HookReport.String(), ShouldEqual, "report")
+		})
+	})
+}
@@ -0,0 +1,67 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync/atomic"
+	"time"
+)
+
+// idAlphabet is Crockford's base32, which avoids letters that are easily
+// confused when read out over the phone (I, L, O and U).
+const idAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// IDLen is the length of an error ID.
+const IDLen = 26
+
+var idInError atomic.Bool
+
+// SetIDInError controls whether Error() ends with the error's ID, as
+// " [id: 01HV...]".  It is off by default, so that Error() stays the
+// same for equal errors.
+func SetIDInError(on bool) {
+	idInError.Store(on)
+}
+
+// ID returns a unique reference for the error, suitable for showing to
+// users and searching for in logs.  IDs are 26 characters of Crockford
+// base32: 48 bits of creation time in milliseconds followed by 80 random
+// bits, so they sort by creation time.  The ID is generated on first use
+// and then fixed for the life of the error.
+func (e Error) ID() string {
+	if e == nil {
+		return ""
+	}
+
+	if id := e.id.Load(); id != nil {
+		return *id
+	}
+
+	id := newID(e.createdAt)
+	e.id.CompareAndSwap(nil, &id)
+
+	return *e.id.Load()
+}
+
+func newID(ts time.Time) string {
+	var buf [16]byte
+
+	binary.BigEndian.PutUint64(buf[:8], uint64(ts.UnixMilli())<<16) // nolint: gosec
+	_, _ = rand.Read(buf[6:])
+
+	// 128 bits as 26 base32 digits, the first carrying only 3 bits.
+	hi := binary.BigEndian.Uint64(buf[:8])
+	lo := binary.BigEndian.Uint64(buf[8:])
+
+	var out [IDLen]byte
+
+	for i := IDLen - 1; i >= 0; i-- {
+		out[i] = idAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(out[:])
+}
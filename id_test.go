@@ -0,0 +1,98 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestID(t *testing.T) {
+	Convey("Verify error reference IDs.", t, func() {
+		Convey("check IDs are stable, unique and well formed", func() {
+			err := e.New[e.DataError]("with id")
+			id := err.ID()
+			So(id, ShouldHaveLength, e.IDLen)
+			So(strings.Trim(id, "0123456789ABCDEFGHJKMNPQRSTVWXYZ"), ShouldBeEmpty)
+			So(err.ID(), ShouldEqual, id)
+			So(e.New[e.DataError]("with id").ID(), ShouldNotEqual, id)
+			So(e.Error(nil).ID(), ShouldBeEmpty)
+		})
+		Convey("check concurrent first use agrees on one ID", func() {
+			err := e.New[e.DataError]("racy")
+			ids := make([]string, 8)
+			var wg sync.WaitGroup
+			for i := range ids {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					ids[i] = err.ID()
+				}(i)
+			}
+			wg.Wait()
+			for _, id := range ids {
+				So(id, ShouldEqual, ids[0])
+			}
+		})
+		Convey("check IDs sort by creation time", func() {
+			ids := []string{}
+			for i := 0; i < 3; i++ {
+				ids = append(ids, e.New[e.DataError]("sorted").ID())
+				time.Sleep(2 * time.Millisecond)
+			}
+			So(sort.StringsAreSorted(ids), ShouldBeTrue)
+		})
+		Convey("check the ID appears in output", func() {
+			err := e.New[e.NotFoundError]("missing widget")
+			So(err.JSON()["ID"], ShouldEqual, err.ID())
+			So(err.SummarizeConsole(), ShouldContainSubstring, err.ID())
+			So(err.Error(), ShouldEqual, "missing widget")
+			So(err.Error(), ShouldEqual, e.New[e.NotFoundError]("missing widget").Error())
+
+			e.SetIDInError(true)
+			defer e.SetIDInError(false)
+			So(err.Error(), ShouldEqual, "missing widget [id: "+err.ID()+"]")
+		})
+		Convey("check the ID shown to users matches the logged error", func() {
+			err := e.Wrap(e.New[e.NotFoundError]("missing widget"), "loading order")
+			var log bytes.Buffer
+			r := e.NewReporter(e.ReporterConfig{Sinks: []e.Sink{e.WriterSink{W: &log}}})
+			So(r.Report(err), ShouldBeTrue)
+			So(r.Close(context.Background()), ShouldBeNil)
+			var logged e.ErrorJSON
+			So(json.Unmarshal(log.Bytes(), &logged), ShouldBeNil)
+
+			rec := httptest.NewRecorder()
+			e.WriteProblem(rec, err)
+			var p e.Problem
+			So(json.Unmarshal(rec.Body.Bytes(), &p), ShouldBeNil)
+			So(p.ID, ShouldNotBeEmpty)
+			So(logged.ID, ShouldEqual, p.ID)
+		})
+		Convey("check problem+json responses", func() {
+			err := e.New[e.NotFoundError]("missing widget")
+			rec := httptest.NewRecorder()
+			e.WriteProblem(rec, err)
+			So(rec.Code, ShouldEqual, http.StatusNotFound)
+			So(rec.Header().Get("Content-Type"), ShouldEqual, e.ProblemContentType)
+			var p e.Problem
+			So(json.Unmarshal(rec.Body.Bytes(), &p), ShouldBeNil)
+			So(p, ShouldResemble, e.Problem{Title: "Not Found", Status: 404, Detail: e.ClassPublic(e.NotFoundError{}).Message, ID: err.ID()})
+			So(rec.Body.String(), ShouldNotContainSubstring, "widget")
+
+			So(e.New[e.CanceledError]("gone").Problem().Title, ShouldEqual, "CanceledError")
+			So(e.HTTPStatus(e.DataError{}), ShouldEqual, http.StatusInternalServerError)
+		})
+	})
+}
@@ -0,0 +1,277 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// Layout controls how SummarizeConsole arranges its lines.  The zero
+// Layout leaves lines as they are rendered.
+type Layout struct {
+	// Width wraps lines to this many columns, indenting continuation
+	// lines and multi-line values under their path element.  When set
+	// with SetLayout, zero means the width of the terminal being written
+	// to (none if it is not a terminal) and a negative width never wraps.
+	// Widths under 42 columns, the width of the
+	// banners, are raised to 42.
+	Width int
+	// AlignFrames pads the file:line of each path element so that the
+	// function names and messages line up.
+	AlignFrames bool
+	// MaxValueLines collapses values longer than this many lines with a
+	// "… N more lines" marker.  Zero shows values in full.
+	MaxValueLines int
+	// SourceLines shows this many lines of source either side of each
+	// path element, when the source file can be found.  Zero shows none.
+	SourceLines int
+}
+
+// ConsoleOptions controls SummarizeConsoleWith.
+type ConsoleOptions struct {
+	Theme  Theme
+	Layout Layout
+}
+
+// minWrapWidth stops silly widths from producing a column of characters.
+// It is the width of the closing banner, which is never wrapped.
+const minWrapWidth = 42
+
+const (
+	linesMarker = "… %d more lines"
+	lineIndent  = "       "
+	valueIndent = "      "
+)
+
+var (
+	layoutMu sync.RWMutex
+	layout   Layout
+)
+
+// SetLayout sets the layout used by SummarizeConsole and WriteConsole.
+func SetLayout(l Layout) {
+	layoutMu.Lock()
+	defer layoutMu.Unlock()
+
+	layout = l
+}
+
+// CurrentLayout returns the layout set with SetLayout.
+func CurrentLayout() Layout {
+	layoutMu.RLock()
+	defer layoutMu.RUnlock()
+
+	return layout
+}
+
+// TerminalWidth returns the width in columns of the terminal w writes
+// to, taken from $COLUMNS if set or else asked of the terminal.  It is
+// zero if w is not a terminal or the width is unknown.
+func TerminalWidth(w io.Writer) int {
+	if !writesToTerminal(w) {
+		return 0
+	}
+
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+
+	if f, ok := w.(interface{ Fd() uintptr }); ok {
+		if cols, _, err := term.GetSize(int(f.Fd())); err == nil {
+			return cols
+		}
+	}
+
+	return 0
+}
+
+// ConsoleOptionsFor returns the options used for output written to w:
+// its theme (see ThemeFor) and the current layout, with the width
+// detected from w if not set.
+func ConsoleOptionsFor(w io.Writer) ConsoleOptions {
+	l := CurrentLayout()
+	if l.Width == 0 {
+		l.Width = TerminalWidth(w)
+	}
+
+	return ConsoleOptions{Theme: ThemeFor(w), Layout: l}
+}
+
+// consoleLayout applies a Layout to the lines of SummarizeConsole.
+type consoleLayout struct {
+	Layout
+	theme Theme
+}
+
+func newConsoleLayout(opts ConsoleOptions) consoleLayout {
+	l := consoleLayout{Layout: opts.Layout, theme: opts.Theme}
+	if l.Width > 0 && l.Width < minWrapWidth {
+		l.Width = minWrapWidth
+	}
+
+	return l
+}
+
+// line wraps a line of output, which ends in a newline.
+func (l consoleLayout) line(str string) string {
+	if l.Width <= 0 {
+		return str
+	}
+
+	out := []string{}
+	for _, ln := range strings.Split(strings.TrimSuffix(str, "\n"), "\n") {
+		out = append(out, wrapLine(ln, l.Width, lineIndent)...)
+	}
+
+	return strings.Join(out, "\n") + "\n"
+}
+
+// value wraps, indents and collapses a rendered value.
+func (l consoleLayout) value(str string) string {
+	if l.Width <= 0 && l.MaxValueLines <= 0 {
+		return str
+	}
+
+	lines := strings.Split(strings.TrimSuffix(str, "\n"), "\n")
+	out := make([]string, 0, len(lines))
+
+	for i, ln := range lines {
+		if l.Width <= 0 {
+			out = append(out, ln)
+
+			continue
+		}
+
+		if i > 0 {
+			ln = valueIndent + ln
+		}
+
+		out = append(out, wrapLine(ln, l.Width, valueIndent)...)
+	}
+
+	if l.MaxValueLines > 0 && len(out) > l.MaxValueLines {
+		marker := valueIndent + l.theme.Label.Sprintf(linesMarker, len(out)-l.MaxValueLines)
+		if strings.Contains(str, "\x1b") {
+			marker = ansiReset + marker
+		}
+
+		out = append(out[:l.MaxValueLines], marker)
+	}
+
+	return strings.Join(out, "\n") + "\n"
+}
+
+// snippet cuts the lines of a source snippet to the width, ending cut
+// lines with "…".  Source is not wrapped: continuation lines would read
+// as more source.
+func (l consoleLayout) snippet(str string) string {
+	if l.Width <= 0 {
+		return str
+	}
+
+	lines := strings.Split(strings.TrimSuffix(str, "\n"), "\n")
+	for i, ln := range lines {
+		if cut, _ := breakPoint(ln, l.Width, len(ln)); cut < 0 {
+			continue
+		}
+
+		cut, _ := breakPoint(ln, l.Width-1, len(ln))
+		lines[i] = ln[:cut]
+
+		if strings.Contains(ln, "\x1b") {
+			lines[i] += ansiReset
+		}
+
+		lines[i] += "…"
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// frames returns the file:line of each path element, padded to the same
+// width if the layout aligns them.
+func (l consoleLayout) frames(path []PathElement) []string {
+	ret := make([]string, 0, len(path))
+	widest := 0
+
+	for _, pe := range path {
+		loc := pe.FileName + ":" + strconv.Itoa(pe.LineNumber)
+		if n := utf8.RuneCountInString(loc); n > widest {
+			widest = n
+		}
+
+		ret = append(ret, loc)
+	}
+
+	if l.AlignFrames {
+		for i, loc := range ret {
+			ret[i] = loc + strings.Repeat(" ", widest-utf8.RuneCountInString(loc))
+		}
+	}
+
+	return ret
+}
+
+// wrapLine breaks line into lines of at most width visible columns,
+// preferring to break at spaces and starting continuation lines with
+// indent.  ANSI escapes take no columns and are never split.
+func wrapLine(line string, width int, indent string) []string {
+	if utf8.RuneCountInString(indent) >= width/2 {
+		indent = ""
+	}
+
+	minBreak := len(indent)
+	ret := []string{}
+
+	for {
+		cut, resume := breakPoint(line, width, minBreak)
+		if cut < 0 {
+			break
+		}
+
+		ret = append(ret, strings.TrimRight(line[:cut], " "))
+		line = indent + strings.TrimLeft(line[resume:], " ")
+	}
+
+	return append(ret, line)
+}
+
+// breakPoint finds where to break str to fit width columns, returning
+// -1 if it already fits.  Breaks at the last space after minBreak bytes
+// if there is one, and otherwise mid-word.
+func breakPoint(str string, width, minBreak int) (int, int) {
+	cols, space := 0, -1
+
+	for i := 0; i < len(str); {
+		if skip := ansiSkip(str[i:]); skip > 0 {
+			i += skip
+
+			continue
+		}
+
+		if str[i] == ' ' {
+			space = i
+		}
+
+		if cols == width {
+			if space > minBreak {
+				return space, space + 1
+			}
+
+			return i, i
+		}
+
+		_, size := utf8.DecodeRuneInString(str[i:])
+		cols++
+		i += size
+	}
+
+	return -1, -1
+}
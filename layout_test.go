@@ -0,0 +1,100 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var layoutANSIRe = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func fLayout() e.Error {
+	err := e.NewWithVals[e.DataError]("this is a rather long message that should wrap around the configured width", func() e.Values {
+		return e.Values{
+			e.V{"sql", "SELECT a, b, c FROM some_table WHERE x = 1 AND y = 2 AND z = 3 ORDER BY a"},
+			map[string]int{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5},
+		}
+	})
+	return fLayoutWrap(err)
+}
+
+func fLayoutWrap(err e.Error) e.Error {
+	return e.Wrap(err, "wrapped")
+}
+
+func TestLayout(t *testing.T) {
+	Convey("Verify terminal width aware layout.", t, func() {
+		Convey("check lines are wrapped and indented", func() {
+			for _, th := range []e.Theme{e.ThemeMonochrome, e.ThemeDark} {
+				sum := fLayout().SummarizeConsoleWith(e.ConsoleOptions{Theme: th, Layout: e.Layout{Width: 60}})
+				plain := layoutANSIRe.ReplaceAllString(sum, "")
+				for _, line := range strings.Split(plain, "\n") {
+					So(utf8.RuneCountInString(line), ShouldBeLessThanOrEqualTo, 60)
+				}
+				So(plain, ShouldContainSubstring, "\n       the configured width\n")
+				So(plain, ShouldContainSubstring, "- --$  sql : SELECT a, b, c FROM some_table WHERE x = 1 AND\n      y = 2 AND z = 3 ORDER BY a\n")
+				So(plain, ShouldContainSubstring, "\n        (string) \"a\": (int) 1,\n")
+			}
+		})
+		Convey("check no layout leaves lines alone", func() {
+			sum := fLayout().SummarizeConsoleTheme(e.ThemeMonochrome)
+			So(sum, ShouldContainSubstring, "-> this is a rather long message that should wrap around the configured width\n")
+			So(sum, ShouldContainSubstring, "\n  (string) \"a\": (int) 1,\n")
+		})
+		Convey("check frames are aligned", func() {
+			sum := fLayout().SummarizeConsoleWith(e.ConsoleOptions{Theme: e.ThemeMonochrome, Layout: e.Layout{AlignFrames: true}})
+			cols := []int{}
+			for _, line := range strings.Split(sum, "\n") {
+				if strings.HasPrefix(line, "- -> ") && strings.Contains(line, "layout_test.go:") {
+					cols = append(cols, strings.Index(line, " github.com/paudley/e_test.fLayout"))
+				}
+			}
+			So(cols, ShouldHaveLength, 2)
+			So(cols[0], ShouldBeGreaterThan, 0)
+			So(cols[1], ShouldEqual, cols[0])
+		})
+		Convey("check long values are collapsed", func() {
+			sum := fLayout().SummarizeConsoleWith(e.ConsoleOptions{Theme: e.ThemeMonochrome, Layout: e.Layout{MaxValueLines: 3}})
+			So(sum, ShouldContainSubstring, "  (string) \"b\": (int) 2,\n      … 4 more lines\n")
+			So(sum, ShouldNotContainSubstring, `"c"`)
+		})
+		Convey("check the width is only detected for terminals", func() {
+			defer e.SetLayout(e.CurrentLayout())
+			var buf bytes.Buffer
+			So(e.TerminalWidth(&buf), ShouldEqual, 0)
+			So(e.ConsoleOptionsFor(&buf).Layout.Width, ShouldEqual, 0)
+			e.SetLayout(e.Layout{Width: 50, MaxValueLines: 10})
+			So(e.ConsoleOptionsFor(&buf).Layout, ShouldResemble, e.Layout{Width: 50, MaxValueLines: 10})
+			So(fLayout().WriteConsole(&buf), ShouldBeNil)
+			So(buf.String(), ShouldContainSubstring, "\n       width\n")
+		})
+		Convey("check the width of a terminal", func() {
+			defer e.SetTerminalCheck(nil)
+			var buf bytes.Buffer
+			e.SetTerminalCheck(func(io.Writer) bool { return true })
+			t.Setenv("COLUMNS", "72")
+			So(e.TerminalWidth(&buf), ShouldEqual, 72)
+			t.Setenv("COLUMNS", "")
+			So(e.TerminalWidth(&buf), ShouldEqual, 0)
+		})
+		Convey("check narrow widths are raised to 42 columns", func() {
+			sum := fLayout().SummarizeConsoleWith(e.ConsoleOptions{Theme: e.ThemeMonochrome, Layout: e.Layout{Width: 10}})
+			longest := 0
+			for _, line := range strings.Split(sum, "\n") {
+				if n := utf8.RuneCountInString(line); n > longest {
+					longest = n
+				}
+			}
+			So(longest, ShouldBeGreaterThan, 10)
+			So(longest, ShouldBeLessThanOrEqualTo, 42)
+		})
+	})
+}
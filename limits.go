@@ -0,0 +1,243 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"unicode/utf8"
+)
+
+// Limits caps how much of an error is rendered by SummarizeConsole and
+// JSON so that a single large value cannot blow up a log line.  A zero
+// field disables that limit.
+type Limits struct {
+	// MaxValueBytes caps the rendered size of a single value.
+	MaxValueBytes int
+	// MaxDepth caps how deeply nested structs, maps, slices and
+	// pointers are dumped.
+	MaxDepth int
+	// MaxValues caps the number of values rendered per path element.
+	MaxValues int
+	// MaxReportBytes caps the size of the whole rendered report.
+	MaxReportBytes int
+	// MaxContextDepth caps how deeply context field values (see
+	// RegisterContextKey) are dumped.  They are shown inline, so this
+	// is normally lower than MaxDepth.
+	MaxContextDepth int
+}
+
+// DefaultLimits are the limits in effect until SetLimits is called.
+var DefaultLimits = Limits{
+	MaxValueBytes:   16 << 10,
+	MaxDepth:        10,
+	MaxValues:       50,
+	MaxReportBytes:  256 << 10,
+	MaxContextDepth: 3,
+}
+
+// Truncation accounts for what was dropped from a rendered error.
+type Truncation struct {
+	// Values is the number of values dropped entirely.
+	Values int `json:",omitempty"`
+	// Bytes is the number of rendered bytes cut off.
+	Bytes int `json:",omitempty"`
+	// Depth is the number of nested values cut off by MaxDepth.
+	Depth int `json:",omitempty"`
+}
+
+const (
+	depthMarker       = "<max depth %d reached>"
+	bytesMarker       = "… [truncated %d bytes]"
+	bodyCutMarker     = "… [truncated after %d bytes]"
+	bodyNotReadMarker = "… [body not read, attach a SnapshotRequest or SnapshotResponse to show it]"
+	valuesMarker      = "… [%d more values]"
+	ansiReset         = "\x1b[0m"
+	ansiEscapeStart   = '\x1b'
+)
+
+var (
+	limitsMu      sync.RWMutex
+	currentLimits = DefaultLimits
+)
+
+// SetLimits replaces the rendering limits for all errors.
+func SetLimits(lim Limits) {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+
+	currentLimits = lim
+}
+
+// CurrentLimits returns the rendering limits in effect.
+func CurrentLimits() Limits {
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+
+	return currentLimits
+}
+
+// Dropped reports whether anything was truncated.
+func (t Truncation) Dropped() bool {
+	return t.Values > 0 || t.Bytes > 0 || t.Depth > 0
+}
+
+// isContainer reports whether rv is something MaxDepth should cut off.
+// Types that know how to print themselves are left alone.
+func isContainer(rv reflect.Value) bool {
+	if rv.CanInterface() {
+		switch rv.Interface().(type) {
+		case fmt.Stringer, error:
+			return false
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Array:
+		return true
+	case reflect.Slice:
+		return rv.Type().Elem().Kind() != reflect.Uint8
+	case reflect.Pointer, reflect.Interface:
+		return !rv.IsNil() && isContainer(rv.Elem())
+	default:
+		return false
+	}
+}
+
+// ansiSkip returns the length of the ANSI escape sequence at the start
+// of str, or zero if there isn't one.
+func ansiSkip(str string) int {
+	if len(str) < 2 || str[0] != ansiEscapeStart || str[1] != '[' {
+		return 0
+	}
+
+	for i := 2; i < len(str); i++ {
+		if str[i] >= '@' && str[i] <= '~' {
+			return i + 1
+		}
+	}
+
+	return len(str)
+}
+
+// visibleLen is the length of str in bytes, ignoring ANSI escapes.
+func visibleLen(str string) int {
+	n := 0
+
+	for i := 0; i < len(str); {
+		if skip := ansiSkip(str[i:]); skip > 0 {
+			i += skip
+
+			continue
+		}
+
+		n++
+		i++
+	}
+
+	return n
+}
+
+// truncateRendered cuts str down to limit visible bytes without breaking
+// ANSI escapes or UTF-8 runes, returning the result and how many
+// visible bytes were dropped.  A limit of zero means no limit.
+func truncateRendered(str string, limit int) (string, int) {
+	total := visibleLen(str)
+	if limit <= 0 || total <= limit {
+		return str, 0
+	}
+
+	n, escaped, cut := 0, false, len(str)
+
+	for i := 0; i < len(str); {
+		if skip := ansiSkip(str[i:]); skip > 0 {
+			escaped = true
+			i += skip
+
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(str[i:])
+		if n+size > limit {
+			cut = i
+
+			break
+		}
+
+		n += size
+		i += size
+	}
+
+	ret := str[:cut]
+	if escaped {
+		ret += ansiReset
+	}
+
+	return ret, total - n
+}
+
+// limitValue truncates a single rendered value, adding a marker and
+// accounting for what was cut.
+func limitValue(str string, limit int, trunc *Truncation) string {
+	ret, dropped := truncateRendered(str, limit)
+	if dropped == 0 {
+		return str
+	}
+
+	trunc.Bytes += dropped
+
+	return ret + fmt.Sprintf(bytesMarker, dropped)
+}
+
+// jsonSize is the encoded size of a rendered JSON value.
+func jsonSize(val any) int {
+	if str, ok := val.(string); ok {
+		return len(str)
+	}
+
+	buf, err := json.Marshal(val)
+	if err != nil {
+		return 0
+	}
+
+	return len(buf)
+}
+
+// jsonString is the string form of a rendered JSON value.
+func jsonString(val any) string {
+	if str, ok := val.(string); ok {
+		return str
+	}
+
+	buf, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+
+	return string(buf)
+}
+
+// limitJSON truncates a rendered JSON value.  Structured values that
+// are too large are replaced by their truncated encoding.
+func limitJSON(val any, limit int, trunc *Truncation) any {
+	if limit <= 0 {
+		return val
+	}
+
+	if str, ok := val.(string); ok {
+		return limitValue(str, limit, trunc)
+	}
+
+	if jsonSize(val) <= limit {
+		return val
+	}
+
+	buf, err := json.Marshal(val)
+	if err != nil {
+		return val
+	}
+
+	return limitValue(string(buf), limit, trunc)
+}
@@ -0,0 +1,92 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type nested struct {
+	Name  string
+	Child *nested
+}
+
+func fLargeValues() e.Error {
+	return e.NewWithVals[e.DataError]("big payload", func() e.Values {
+		return e.Values{
+			e.V{K: "payload", I: strings.Repeat("x", 5000)},
+			e.V{K: "tree", I: &nested{Name: "a", Child: &nested{Name: "b", Child: &nested{Name: "c"}}}},
+			"three",
+			"four",
+		}
+	})
+}
+
+func TestLimits(t *testing.T) {
+	Convey("Verify that rendered errors respect size limits.", t, func() {
+		e.SetLimits(e.Limits{MaxValueBytes: 400, MaxDepth: 2, MaxValues: 3})
+		defer e.SetLimits(e.DefaultLimits)
+
+		Convey("check json truncation and accounting", func() {
+			js := fLargeValues().JSON()
+			vals := js["Path"].([]e.ErrorPathJSON)[0].Values
+			So(len(vals), ShouldEqual, 3)
+			first := vals[0]
+			So(first, ShouldContainSubstring, "… [truncated")
+			So(len(first), ShouldBeLessThan, 500)
+			So(vals[1], ShouldContainSubstring, "<max depth 2 reached>")
+			So(vals[1], ShouldNotContainSubstring, `"c"`)
+			trunc, ok := js["Truncated"].(e.Truncation)
+			So(ok, ShouldBeTrue)
+			So(trunc.Values, ShouldEqual, 1)
+			So(trunc.Depth, ShouldEqual, 1)
+			So(trunc.Bytes, ShouldBeGreaterThan, 4500)
+		})
+		Convey("check console truncation markers", func() {
+			sum := fLargeValues().SummarizeConsole()
+			So(sum, ShouldContainSubstring, "… [truncated")
+			So(sum, ShouldContainSubstring, "… [1 more values]")
+			So(sum, ShouldNotContainSubstring, strings.Repeat("x", 500))
+		})
+		Convey("check total report size", func() {
+			e.SetLimits(e.Limits{MaxReportBytes: 300})
+			sum := fLargeValues().SummarizeConsoleTheme(e.ThemeMonochrome)
+			So(sum, ShouldContainSubstring, "… [truncated")
+			So(sum, ShouldEndWith, "!! -----------------------------Error-- !!\n")
+			So(len(sum), ShouldBeLessThan, 1000)
+		})
+		Convey("check truncation survives decoding", func() {
+			buf, err := json.Marshal(fLargeValues().JSON())
+			So(err, ShouldBeNil)
+			var ej e.ErrorJSON
+			So(json.Unmarshal(buf, &ej), ShouldBeNil)
+			So(ej.Truncated, ShouldNotBeNil)
+			So(ej.Truncated.Values, ShouldEqual, 1)
+			So(ej.Truncated.Depth, ShouldEqual, 1)
+
+			buf, err = json.Marshal(fBad().JSON())
+			So(err, ShouldBeNil)
+			ej = e.ErrorJSON{}
+			So(json.Unmarshal(buf, &ej), ShouldBeNil)
+			So(ej.Truncated, ShouldBeNil)
+		})
+		Convey("check truncated context fields are accounted", func() {
+			e.SetLimits(e.Limits{MaxContextDepth: 1})
+			e.RegisterContextKey("limits_tree", ctxKey("limits_tree"))
+			ctx := context.WithValue(context.Background(), ctxKey("limits_tree"), map[string]any{"a": map[string]any{"b": 1}})
+			trunc, ok := e.NewWithContext[e.DataError](ctx, "deep context").JSON()["Truncated"].(e.Truncation)
+			So(ok, ShouldBeTrue)
+			So(trunc.Depth, ShouldEqual, 1)
+		})
+		Convey("check small errors are untouched", func() {
+			_, ok := fBad().JSON()["Truncated"]
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
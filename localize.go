@@ -0,0 +1,433 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// PluralRule returns the CLDR plural category ("zero", "one", "two",
+// "few", "many" or "other") for a count in some language.
+type PluralRule func(n float64) string
+
+// PluralEnglish is the rule for English and most Germanic languages.
+func PluralEnglish(n float64) string {
+	if n == 1 {
+		return "one"
+	}
+
+	return "other"
+}
+
+// PluralFrench is the rule for French, where zero is singular.
+func PluralFrench(n float64) string {
+	if n >= 0 && n < 2 {
+		return "one"
+	}
+
+	return "other"
+}
+
+// PluralNone is the rule for languages without plural forms.
+func PluralNone(float64) string {
+	return "other"
+}
+
+// DefaultCountKey is the value key used to choose a plural form when
+// the catalog entry does not name one.
+const DefaultCountKey = "count"
+
+// catalogEntry is one message template.  Plural entries have several
+// forms and the key of the value holding the count; plain entries use
+// the "other" form.
+type catalogEntry struct {
+	countKey string
+	forms    map[string]string
+}
+
+// UnmarshalJSON accepts either a template string or an object of plural
+// forms, optionally naming the count value with "var":
+//
+//	{"var": "files", "one": "{files} fichier", "other": "{files} fichiers"}
+func (ce *catalogEntry) UnmarshalJSON(data []byte) error {
+	var tmpl string
+	if err := json.Unmarshal(data, &tmpl); err == nil {
+		ce.forms = map[string]string{"other": tmpl}
+
+		return nil
+	}
+
+	forms := map[string]string{}
+	if err := json.Unmarshal(data, &forms); err != nil {
+		return err
+	}
+
+	ce.countKey = forms["var"]
+	delete(forms, "var")
+	ce.forms = forms
+
+	return nil
+}
+
+// Catalog holds translated message templates by language.  Templates are
+// found by the message ID of an error (see PublicInfo), the format of a
+// Newf or Wrapf message, or its class key (see ClassKey).
+type Catalog struct {
+	mu          sync.RWMutex
+	defaultLang string
+	messages    map[string]map[string]catalogEntry
+	fallbacks   map[string][]string
+	plurals     map[string]PluralRule
+}
+
+// NewCatalog returns an empty catalog whose fallback chains end with
+// defaultLang.
+func NewCatalog(defaultLang string) *Catalog {
+	return &Catalog{
+		defaultLang: normalizeLang(defaultLang),
+		messages:    map[string]map[string]catalogEntry{},
+		fallbacks:   map[string][]string{},
+		plurals: map[string]PluralRule{
+			"en": PluralEnglish,
+			"fr": PluralFrench,
+			"ja": PluralNone,
+			"zh": PluralNone,
+		},
+	}
+}
+
+// ClassKey returns the catalog ID for errors of class ec, e.g.
+// "defaultErrors.TimeoutError".
+func ClassKey(ec ErrorClass) string {
+	return ec.Area() + "." + ec.What()
+}
+
+func normalizeLang(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+}
+
+// baseLang strips any region or script, "fr-ca" becoming "fr".
+func baseLang(lang string) string {
+	if i := strings.IndexByte(lang, '-'); i > 0 {
+		return lang[:i]
+	}
+
+	return lang
+}
+
+func (c *Catalog) add(lang, id string, entry catalogEntry) {
+	lang = normalizeLang(lang)
+	if c.messages[lang] == nil {
+		c.messages[lang] = map[string]catalogEntry{}
+	}
+
+	c.messages[lang][id] = entry
+}
+
+// Add sets the template for message id in lang.
+func (c *Catalog) Add(lang, id, tmpl string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.add(lang, id, catalogEntry{forms: map[string]string{"other": tmpl}})
+}
+
+// AddPlural sets the plural forms for message id in lang.  The form is
+// chosen by the value with key countKey, or DefaultCountKey if empty.
+func (c *Catalog) AddPlural(lang, id, countKey string, forms map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.add(lang, id, catalogEntry{countKey: countKey, forms: forms})
+}
+
+// Load reads a JSON catalog for lang, an object mapping message IDs to
+// templates or plural forms, and merges it into c.
+func (c *Catalog) Load(lang string, r io.Reader) Error {
+	entries := map[string]catalogEntry{}
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return WrapErrorMsg[ValidationError](err, "malformed message catalog").AddValue("lang", lang)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, entry := range entries {
+		c.add(lang, id, entry)
+	}
+
+	return nil
+}
+
+// LoadFS loads every catalog in fsys matching pattern, taking the
+// language from the file name: "locales/fr-CA.json" is French (Canada).
+func (c *Catalog) LoadFS(fsys fs.FS, pattern string) Error {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return WrapErrorMsg[ValidationError](err, "bad catalog pattern").AddValue("pattern", pattern)
+	}
+
+	for _, name := range names {
+		if err := c.loadFile(fsys, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Catalog) loadFile(fsys fs.FS, name string) Error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return WrapErrorMsg[FileError](err, "cannot open message catalog")
+	}
+	defer f.Close()
+
+	base := path.Base(name)
+
+	if eErr := c.Load(strings.TrimSuffix(base, path.Ext(base)), f); eErr != nil {
+		return eErr.AddValue("file", name)
+	}
+
+	return nil
+}
+
+// SetFallback sets the languages tried, in order, when lang has no
+// template for a message.  The base language and the default language
+// are always tried after these.
+func (c *Catalog) SetFallback(lang string, chain ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	norm := make([]string, 0, len(chain))
+	for _, l := range chain {
+		norm = append(norm, normalizeLang(l))
+	}
+
+	c.fallbacks[normalizeLang(lang)] = norm
+}
+
+// SetPluralRule sets the plural rule for lang and its regional variants.
+func (c *Catalog) SetPluralRule(lang string, rule PluralRule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.plurals[normalizeLang(lang)] = rule
+}
+
+// Chain returns the languages tried for lang, in order.
+func (c *Catalog) Chain(lang string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.chain(normalizeLang(lang))
+}
+
+func (c *Catalog) chain(lang string) []string {
+	chain := []string{}
+	seen := map[string]bool{}
+
+	var walk func(l string)
+	walk = func(l string) {
+		if l == "" || seen[l] {
+			return
+		}
+
+		seen[l] = true
+		chain = append(chain, l)
+
+		for _, fb := range c.fallbacks[l] {
+			walk(fb)
+		}
+
+		walk(baseLang(l))
+	}
+
+	walk(lang)
+	walk(c.defaultLang)
+
+	return chain
+}
+
+func (c *Catalog) pluralRule(lang string) PluralRule {
+	if rule, ok := c.plurals[lang]; ok {
+		return rule
+	}
+
+	if rule, ok := c.plurals[baseLang(lang)]; ok {
+		return rule
+	}
+
+	return PluralEnglish
+}
+
+// Localize returns the user-facing message for err in lang.  In each
+// language of the fallback chain the error's message ID is tried, then
+// the format of its outermost Newf or Wrapf message, then its class key;
+// if none has a template the public message is returned untranslated.
+// A public message set on the error itself is preferred to the class
+// key's template.
+//
+// Templates refer to keyed values as {key}.  Templates found by format
+// also see the args of that message as {arg1}, {arg2}, ...  Values are
+// redacted as in JSON output, whatever the redaction policy.
+func (c *Catalog) Localize(err Error, lang string) string {
+	if err == nil {
+		return ""
+	}
+
+	pub := err.Public()
+	ids := []string{}
+
+	if pub.MessageID != "" {
+		ids = append(ids, pub.MessageID)
+	}
+
+	var format *PathElement
+
+	for i := len(err.path) - 1; i >= 0; i-- {
+		if err.path[i].Template != "" {
+			format = &err.path[i]
+			ids = append(ids, format.Template)
+
+			break
+		}
+	}
+
+	if own, ok := err.ownPublic(); !ok || own.Message == "" {
+		ids = append(ids, ClassKey(err.Class()))
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, l := range c.chain(normalizeLang(lang)) {
+		for _, id := range ids {
+			entry, ok := c.messages[l][id]
+			if !ok {
+				continue
+			}
+
+			vals := localizeValues(AllValues(err))
+			if format != nil && id == format.Template {
+				for k, v := range localizeValues(elementValues(*format)) {
+					vals[k] = v
+				}
+			}
+
+			return c.render(l, entry, vals)
+		}
+	}
+
+	return pub.Message
+}
+
+func (c *Catalog) render(lang string, entry catalogEntry, vals map[string]any) string {
+	tmpl := entry.forms["other"]
+
+	if len(entry.forms) > 1 {
+		key := entry.countKey
+		if key == "" {
+			key = DefaultCountKey
+		}
+
+		if n, ok := toCount(vals[key]); ok {
+			if form, ok := entry.forms[c.pluralRule(lang)(n)]; ok {
+				tmpl = form
+			}
+		}
+	}
+
+	return interpolate(tmpl, vals)
+}
+
+// localizeValues redacts keyed values for use in templates, later ones
+// winning.
+func localizeValues(kvs []KeyValue) map[string]any {
+	vals := map[string]any{}
+	depth := CurrentLimits().MaxDepth
+
+	for _, kv := range kvs {
+		if v, ok := prepareValue(outputPublic, V{K: kv.Key, I: kv.Value}, depth, &Truncation{}).(V); ok {
+			vals[kv.Key] = v.I
+		}
+	}
+
+	return vals
+}
+
+func toCount(val any) (float64, bool) {
+	rv := reflect.ValueOf(val)
+
+	switch rv.Kind() { // nolint: exhaustive
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// interpolate replaces {key} in tmpl with the matching value.  Unknown
+// keys are left as they are.
+func interpolate(tmpl string, vals map[string]any) string {
+	var sb strings.Builder
+
+	for {
+		open := strings.IndexByte(tmpl, '{')
+		if open < 0 {
+			break
+		}
+
+		end := strings.IndexByte(tmpl[open:], '}')
+		if end < 0 {
+			break
+		}
+
+		end += open
+		sb.WriteString(tmpl[:open])
+
+		if val, ok := vals[tmpl[open+1:end]]; ok {
+			fmt.Fprint(&sb, val)
+		} else {
+			sb.WriteString(tmpl[open : end+1])
+		}
+
+		tmpl = tmpl[end+1:]
+	}
+
+	sb.WriteString(tmpl)
+
+	return sb.String()
+}
+
+var defaultCatalog atomic.Pointer[Catalog]
+
+// SetCatalog sets the catalog used by Localize.  Pass nil to disable
+// localization.
+func SetCatalog(c *Catalog) {
+	defaultCatalog.Store(c)
+}
+
+// Localize returns the user-facing message for the error in lang using
+// the catalog set with SetCatalog, or the public message if there is
+// none.
+func (e Error) Localize(lang string) string {
+	c := defaultCatalog.Load()
+	if c == nil {
+		return e.PublicMessage()
+	}
+
+	return c.Localize(e, lang)
+}
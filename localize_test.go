@@ -0,0 +1,86 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var testCatalogs = fstest.MapFS{
+	"locales/en.json": {Data: []byte(`{
+		"defaultErrors.TimeoutError": "The request took too long.",
+		"report.files": {"var": "files", "one": "{files} file could not be read from {dir}.", "other": "{files} files could not be read from {dir}."}
+	}`)},
+	"locales/fr.json": {Data: []byte(`{
+		"defaultErrors.TimeoutError": "La requête a pris trop de temps.",
+		"report.files": {"var": "files", "one": "{files} fichier illisible dans {dir}.", "other": "{files} fichiers illisibles dans {dir}."},
+		"login.failed": "Connexion refusée pour {user} ({password})."
+	}`)},
+	"locales/fr-CA.json": {Data: []byte(`{
+		"login.failed": "Connexion refusée pour {user}."
+	}`)},
+}
+
+func newFilesError(n int) e.Error {
+	return e.NewWithVals[e.FileError]("readdir failed", func() e.Values {
+		return e.Values{e.V{"files", n}, e.V{"dir", "/srv/reports"}}
+	}).WithMessageID("report.files")
+}
+
+func TestLocalize(t *testing.T) {
+	Convey("Verify message localization.", t, func() {
+		cat := e.NewCatalog("en")
+		So(cat.LoadFS(testCatalogs, "locales/*.json"), ShouldBeNil)
+		e.SetCatalog(cat)
+		defer e.SetCatalog(nil)
+
+		Convey("check class templates", func() {
+			err := e.New[e.TimeoutError]("query exceeded 30s")
+			So(err.Localize("fr"), ShouldEqual, "La requête a pris trop de temps.")
+			So(err.Localize("en-GB"), ShouldEqual, "The request took too long.")
+			So(err.Localize("de"), ShouldEqual, "The request took too long.")
+		})
+		Convey("check plurals and interpolation", func() {
+			So(newFilesError(1).Localize("en"), ShouldEqual, "1 file could not be read from /srv/reports.")
+			So(newFilesError(3).Localize("en"), ShouldEqual, "3 files could not be read from /srv/reports.")
+			So(newFilesError(0).Localize("en"), ShouldEqual, "0 files could not be read from /srv/reports.")
+			So(newFilesError(0).Localize("fr"), ShouldEqual, "0 fichier illisible dans /srv/reports.")
+			So(newFilesError(2).Localize("fr_FR"), ShouldEqual, "2 fichiers illisibles dans /srv/reports.")
+		})
+		Convey("check fallback chains", func() {
+			login := e.NewWithVals[e.ValidationError]("bad login", func() e.Values {
+				return e.Values{e.V{"user", "bob"}, e.V{"password", "hunter2"}}
+			}).WithMessageID("login.failed")
+			So(login.Localize("fr-CA"), ShouldEqual, "Connexion refusée pour bob.")
+			So(login.Localize("fr"), ShouldEqual, "Connexion refusée pour bob ("+e.RedactedText+").")
+			So(login.Localize("en"), ShouldEqual, e.ClassPublic(e.ValidationError{}).Message)
+
+			cat.SetFallback("br", "fr")
+			So(cat.Chain("br"), ShouldResemble, []string{"br", "fr", "en"})
+			So(cat.Chain("fr-CA"), ShouldResemble, []string{"fr-ca", "fr", "en"})
+			So(login.Localize("br"), ShouldStartWith, "Connexion refusée pour bob (")
+		})
+		Convey("check a public message set on the error beats the class key", func() {
+			err := e.New[e.TimeoutError]("query exceeded 30s").WithPublicMessage("Reports are slow today.")
+			So(err.Localize("fr"), ShouldEqual, "Reports are slow today.")
+		})
+		Convey("check unknown placeholders and missing catalogs", func() {
+			cat.Add("en", "custom", "Missing {nothing} here.")
+			err := e.New[e.DataError]("x").WithMessageID("custom")
+			So(err.Localize("en"), ShouldEqual, "Missing {nothing} here.")
+
+			e.SetCatalog(nil)
+			So(err.Localize("fr"), ShouldEqual, e.DefaultPublicMessage)
+		})
+		Convey("check malformed catalogs", func() {
+			err := cat.Load("en", strings.NewReader(`{"x": 5}`))
+			So(err, ShouldNotBeNil)
+			So(err.Class(), ShouldEqual, e.ValidationError{})
+		})
+	})
+}
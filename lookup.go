@@ -0,0 +1,139 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+// Precedence decides which annotation wins when the same key appears
+// more than once along an error path.
+type Precedence int
+
+const (
+	// OutermostWins prefers the value attached closest to the caller
+	// that is handling the error.
+	OutermostWins Precedence = iota
+	// InnermostWins prefers the value attached closest to where the
+	// error originated.
+	InnermostWins
+)
+
+// KeyValue is a single keyed annotation (a V) found on an error path
+// along with the path element that carried it.
+type KeyValue struct {
+	Key     string
+	Value   any
+	Element PathElement
+}
+
+// Unwrap exposes the wrapped go error (if any) to errors.Is and errors.As.
+func (e Error) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+
+	return e.originerror
+}
+
+// errorChain returns every Error reachable from err, outermost first.
+// Standard library wrappers (fmt.Errorf with %w, errors.Join) are
+// followed, as are go errors wrapped by WrapError.  Trees built with
+// errors.Join are walked depth first, so every branch is included and
+// earlier branches come before later ones.
+func errorChain(err error) []Error {
+	chain := []Error{}
+	seen := map[Error]bool{}
+
+	var walk func(err error)
+	walk = func(err error) {
+		if err == nil {
+			return
+		}
+
+		if eData, ok := err.(Error); ok { // nolint: errorlint
+			if eData == nil || seen[eData] {
+				return
+			}
+
+			seen[eData] = true
+			chain = append(chain, eData)
+			walk(eData.originerror)
+
+			return
+		}
+
+		switch wrapped := err.(type) { // nolint: errorlint
+		case interface{ Unwrap() []error }:
+			for _, branch := range wrapped.Unwrap() {
+				walk(branch)
+			}
+		case interface{ Unwrap() error }:
+			walk(wrapped.Unwrap())
+		}
+	}
+
+	walk(err)
+
+	return chain
+}
+
+// AllValues returns every keyed annotation found on err and any errors
+// it wraps, ordered from the innermost (origin) path element to the
+// outermost.  Values that are not wrapped in a V have no key and are
+// skipped.
+func AllValues(err error) []KeyValue {
+	chain := errorChain(err)
+	ret := []KeyValue{}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, pe := range chain[i].path {
+			ret = append(ret, elementValues(pe)...)
+		}
+	}
+
+	return ret
+}
+
+// elementValues returns the keyed values of a single path element.
+func elementValues(pe PathElement) []KeyValue {
+	ret := []KeyValue{}
+
+	for _, val := range pe.Values() {
+		if v, ok := val.(V); ok {
+			ret = append(ret, KeyValue{Key: v.K, Value: v.I, Element: pe})
+		}
+	}
+
+	return ret
+}
+
+// Lookup returns the value annotated under key on err, if there is one
+// of type T.  When the key appears more than once the outermost value
+// wins; use LookupWith to choose otherwise.
+func Lookup[T any](err error, key string) (T, bool) {
+	return LookupWith[T](err, key, OutermostWins)
+}
+
+// LookupWith is Lookup with an explicit precedence rule.
+func LookupWith[T any](err error, key string, prec Precedence) (T, bool) {
+	var (
+		ret   T
+		found bool
+	)
+
+	for _, kv := range AllValues(err) {
+		if kv.Key != key {
+			continue
+		}
+
+		val, ok := kv.Value.(T)
+		if !ok {
+			continue
+		}
+
+		ret, found = val, true
+
+		if prec == InnermostWins {
+			break
+		}
+	}
+
+	return ret, found
+}
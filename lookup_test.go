@@ -0,0 +1,88 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func fLookupNested() e.Error {
+	err := e.NewWithVals[e.DataError]("query failed", func() e.Values {
+		return e.Values{
+			"unkeyed",
+			e.V{K: "sql", I: "SELECT 1"},
+			e.V{K: "user_id", I: 17},
+		}
+	})
+
+	return e.WrapWithVals[e.DataError](err, "loading user", func() e.Values {
+		return e.Values{e.V{K: "user_id", I: 42}}
+	})
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+	Convey("Verify that annotations can be read back from errors.", t, func() {
+		Convey("check typed lookup and precedence", func() {
+			err := fLookupNested()
+			sql, ok := e.Lookup[string](err, "sql")
+			So(ok, ShouldBeTrue)
+			So(sql, ShouldEqual, "SELECT 1")
+			uid, ok := e.Lookup[int](err, "user_id")
+			So(ok, ShouldBeTrue)
+			So(uid, ShouldEqual, 42)
+			uid, ok = e.LookupWith[int](err, "user_id", e.InnermostWins)
+			So(ok, ShouldBeTrue)
+			So(uid, ShouldEqual, 17)
+			_, ok = e.Lookup[string](err, "user_id")
+			So(ok, ShouldBeFalse)
+			_, ok = e.Lookup[int](err, "missing")
+			So(ok, ShouldBeFalse)
+		})
+		Convey("check all values are ordered innermost first", func() {
+			kvs := e.AllValues(fLookupNested())
+			So(len(kvs), ShouldEqual, 3)
+			So(kvs[0].Key, ShouldEqual, "sql")
+			So(kvs[0].Element.Msg, ShouldEqual, "query failed")
+			So(kvs[2].Key, ShouldEqual, "user_id")
+			So(kvs[2].Element.FuncName, ShouldEqual, "github.com/paudley/e_test.fLookupNested")
+		})
+		Convey("check lookups work through stdlib wrappers", func() {
+			inner := e.New[e.NotFoundError]("no row").AddValue("table", "users")
+			outer := e.WrapError[e.DataError](fmt.Errorf("repo: %w", inner))
+			wrapped := fmt.Errorf("handler: %w", outer)
+			table, ok := e.Lookup[string](wrapped, "table")
+			So(ok, ShouldBeTrue)
+			So(table, ShouldEqual, "users")
+			_, ok = e.Lookup[string](fmt.Errorf("plain"), "table")
+			So(ok, ShouldBeFalse)
+			_, ok = e.Lookup[string](nil, "table")
+			So(ok, ShouldBeFalse)
+		})
+		Convey("check every branch of joined errors is searched", func() {
+			first := e.New[e.NotFoundError]("no row").AddValue("table", "users")
+			second := e.New[e.DataError]("bad row").AddValue("column", "email")
+			joined := fmt.Errorf("batch: %w", errors.Join(first, fmt.Errorf("row 2: %w", second)))
+			table, ok := e.Lookup[string](joined, "table")
+			So(ok, ShouldBeTrue)
+			So(table, ShouldEqual, "users")
+			column, ok := e.Lookup[string](joined, "column")
+			So(ok, ShouldBeTrue)
+			So(column, ShouldEqual, "email")
+			So(errors.Is(joined, second), ShouldBeTrue)
+
+			outer := e.WrapError[e.UnknownError](joined)
+			column, ok = e.Lookup[string](outer, "column")
+			So(ok, ShouldBeTrue)
+			So(column, ShouldEqual, "email")
+			var target e.Error
+			So(errors.As(joined, &target), ShouldBeTrue)
+			So(target, ShouldEqual, first)
+		})
+	})
+}
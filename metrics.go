@@ -0,0 +1,175 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Metric events.
+const (
+	MetricCreated  = "created"
+	MetricReported = "reported"
+)
+
+// MetricSample is one error counter.
+type MetricSample struct {
+	Event  string
+	Area   string
+	Class  string
+	Origin string
+	Count  uint64
+}
+
+type metricKey struct {
+	event, area, class, origin string
+}
+
+var (
+	metricsMu     sync.Mutex
+	metricCounts  = map[metricKey]uint64{}
+	metricsRemove func()
+	metricsExpvar sync.Once
+)
+
+// EnableMetrics turns on counting of errors as they are created and
+// reported, by area, class and origin function.  Counting is off by
+// default; it is implemented as a Hook.  The counters are published
+// through expvar as "e_errors".
+func EnableMetrics(on bool) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	if on == (metricsRemove != nil) {
+		return
+	}
+
+	if !on {
+		metricsRemove()
+		metricsRemove = nil
+
+		return
+	}
+
+	metricsRemove = AddHook(metricsHook)
+
+	metricsExpvar.Do(func() {
+		expvar.Publish("e_errors", expvar.Func(expvarMetrics))
+	})
+}
+
+func metricsHook(event HookEvent, err Error, _ PathElement) {
+	switch event {
+	case HookCreate:
+		countError(MetricCreated, err)
+	case HookReport:
+		countError(MetricReported, err)
+	case HookWrap, HookSetClass:
+	}
+}
+
+// countError bumps the counter for event.
+func countError(event string, err Error) {
+	if err == nil {
+		return
+	}
+
+	ec := err.Class()
+	key := metricKey{event: event, area: ec.Area(), class: ec.What()}
+
+	if len(err.path) > 0 {
+		key.origin = err.path[0].FuncName
+	}
+
+	metricsMu.Lock()
+	metricCounts[key]++
+	metricsMu.Unlock()
+}
+
+// Metrics returns the current counters sorted by event, area, class and
+// origin.
+func Metrics() []MetricSample {
+	metricsMu.Lock()
+	ret := make([]MetricSample, 0, len(metricCounts))
+
+	for k, n := range metricCounts {
+		ret = append(ret, MetricSample{Event: k.event, Area: k.area, Class: k.class, Origin: k.origin, Count: n})
+	}
+	metricsMu.Unlock()
+
+	sort.Slice(ret, func(i, j int) bool {
+		a, b := ret[i], ret[j]
+		if a.Event != b.Event {
+			return a.Event < b.Event
+		}
+
+		if a.Area != b.Area {
+			return a.Area < b.Area
+		}
+
+		if a.Class != b.Class {
+			return a.Class < b.Class
+		}
+
+		return a.Origin < b.Origin
+	})
+
+	return ret
+}
+
+// ResetMetrics clears all counters.
+func ResetMetrics() {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	metricCounts = map[metricKey]uint64{}
+}
+
+func expvarMetrics() any {
+	ret := map[string]map[string]uint64{}
+
+	for _, m := range Metrics() {
+		if ret[m.Event] == nil {
+			ret[m.Event] = map[string]uint64{}
+		}
+
+		ret[m.Event][m.Area+"/"+m.Class+"/"+m.Origin] = m.Count
+	}
+
+	return ret
+}
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// MetricsHandler serves the counters in the Prometheus text exposition
+// format, as e_errors_created_total and e_errors_reported_total.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		samples := Metrics()
+
+		for _, event := range []string{MetricCreated, MetricReported} {
+			name := "e_errors_" + event + "_total"
+			fmt.Fprintf(w, "# HELP %s Errors %s, by area, class and origin function.\n", name, event)
+			fmt.Fprintf(w, "# TYPE %s counter\n", name)
+
+			for _, m := range samples {
+				if m.Event != event {
+					continue
+				}
+
+				fmt.Fprintf(w, "%s{area=\"%s\",class=\"%s\",origin=\"%s\"} %d\n", name,
+					promLabelEscaper.Replace(m.Area),
+					promLabelEscaper.Replace(m.Class),
+					promLabelEscaper.Replace(m.Origin),
+					m.Count)
+			}
+		}
+	})
+}
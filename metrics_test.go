@@ -0,0 +1,77 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func fMetricsOrigin() e.Error {
+	return e.New[e.DataError]("counted")
+}
+
+func metricFor(event, origin string) uint64 {
+	for _, m := range e.Metrics() {
+		if m.Event == event && m.Origin == origin {
+			return m.Count
+		}
+	}
+	return 0
+}
+
+func TestMetrics(t *testing.T) {
+	Convey("Verify per class error metrics.", t, func() {
+		const origin = "github.com/paudley/e_test.fMetricsOrigin"
+		e.ResetMetrics()
+
+		Convey("check metrics are opt-in", func() {
+			fMetricsOrigin()
+			So(metricFor(e.MetricCreated, origin), ShouldEqual, 0)
+		})
+		Convey("check created and reported counters", func() {
+			e.EnableMetrics(true)
+			defer e.EnableMetrics(false)
+			for i := 0; i < 3; i++ {
+				fMetricsOrigin()
+			}
+			So(metricFor(e.MetricCreated, origin), ShouldEqual, 3)
+
+			classified := e.WrapErrorCtx[e.UnknownError](context.Background(), context.DeadlineExceeded)
+			found := false
+			for _, m := range e.Metrics() {
+				if m.Event == e.MetricCreated && m.Origin == classified.Path()[0].FuncName {
+					found = true
+					So(m.Class, ShouldEqual, "TimeoutError")
+				}
+			}
+			So(found, ShouldBeTrue)
+
+			r := e.NewReporter(e.ReporterConfig{})
+			r.Report(fMetricsOrigin())
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			So(r.Close(ctx), ShouldBeNil)
+			So(metricFor(e.MetricReported, origin), ShouldEqual, 1)
+
+			var vars map[string]map[string]uint64
+			So(json.Unmarshal([]byte(expvar.Get("e_errors").String()), &vars), ShouldBeNil)
+			So(vars["created"]["defaultErrors/DataError/"+origin], ShouldEqual, 4)
+
+			rec := httptest.NewRecorder()
+			e.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			body := rec.Body.String()
+			So(body, ShouldContainSubstring, "# TYPE e_errors_created_total counter\n")
+			So(body, ShouldContainSubstring, `e_errors_created_total{area="defaultErrors",class="DataError",origin="`+origin+`"} 4`)
+			So(body, ShouldContainSubstring, `e_errors_reported_total{area="defaultErrors",class="DataError",origin="`+origin+`"} 1`)
+		})
+	})
+}
@@ -16,40 +16,124 @@
 package e
 
 import (
+	"bytes"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 
 	c "github.com/paudley/colorout"
 )
 
 type ErrorPathJSON struct {
-	Caller string
-	Values []string
+	Caller   string
+	Template string `json:",omitempty"`
+	Values   []string
+	// RawValues holds the values as rendered, in the same order as
+	// Values.  It is only set when a JSON renderer produced a structured
+	// value; Values then holds its JSON encoding.
+	RawValues []any `json:",omitempty"`
 }
 
+// ContextStateJSON decodes the ContextState of JSON output.
+type ContextStateJSON struct {
+	Done      bool
+	Err       string `json:",omitempty"`
+	Cause     string `json:",omitempty"`
+	Deadline  string `json:",omitempty"`
+	Remaining string `json:",omitempty"`
+}
+
+// TraceContextJSON decodes the Trace of JSON output.
+type TraceContextJSON struct {
+	TraceID     string
+	SpanID      string
+	Sampled     bool
+	Traceparent string
+}
+
+// ErrorJSON decodes the output of JSON, for instance from log lines
+// written by a WriterSink.
 type ErrorJSON struct {
-	Context string
-	Message string
-	Path    []ErrorPathJSON
+	Kind          string
+	ID            string
+	Fingerprint   string
+	Context       string
+	ContextFields map[string]any    `json:",omitempty"`
+	ContextState  *ContextStateJSON `json:",omitempty"`
+	Trace         *TraceContextJSON `json:",omitempty"`
+	Message       string
+	Path          []ErrorPathJSON
+	Truncated     *Truncation `json:",omitempty"`
 }
 
 func (e Error) JSON() map[string]any {
 	ret := make(map[string]any)
 	ret["Kind"] = "errorBacktrace"
-	ret["Context"] = e.OriginContextString()
+	ret["ID"] = e.ID()
+	trunc := Truncation{}
+	fields := preparedContextFields(outputJSON, e.contextFields, &trunc)
+	ret["Context"] = contextFieldsString(fields)
+
+	if len(fields) > 0 {
+		cf := make(map[string]any, len(fields))
+		for _, f := range fields {
+			cf[f.Name] = f.Value
+		}
+
+		ret["ContextFields"] = cf
+	}
 	ret["Message"] = e.LastMessage()
+	ret["Fingerprint"] = e.Fingerprint()
+
+	if cs, ok := e.ContextState(); ok {
+		ret["ContextState"] = cs.JSON()
+	}
 
+	if tc, ok := e.Trace(); ok {
+		ret["Trace"] = tc.JSON()
+	}
+
+	lim := CurrentLimits()
+	budget := lim.MaxReportBytes
 	eps := []ErrorPathJSON{}
 	path := e.Path()
 
 	for _, p := range path {
 		errorpath := ErrorPathJSON{
-			Caller: fmt.Sprintf("%s:%d/%s -> %s", p.FileName, p.LineNumber, p.FuncName, p.Msg),
+			Caller:   fmt.Sprintf("%s:%d/%s -> %s", p.FileName, p.LineNumber, p.FuncName, p.Msg),
+			Template: p.Template,
 		}
 
 		vals := p.Values()
+		raw := make([]any, 0, len(vals))
+		structured := false
+
+		for i, v := range vals {
+			if (lim.MaxValues > 0 && i >= lim.MaxValues) || (lim.MaxReportBytes > 0 && budget <= 0) {
+				trunc.Values += len(vals) - i
 
-		for _, v := range vals {
-			errorpath.Values = append(errorpath.Values, c.Sdump(v))
+				break
+			}
+
+			rendered := limitJSON(jsonValue(prepareValue(outputJSON, v, lim.MaxDepth, &trunc)), lim.MaxValueBytes, &trunc)
+			budget -= jsonSize(rendered)
+			raw = append(raw, rendered)
+
+			str, ok := rendered.(string)
+			if !ok {
+				structured = true
+				str = jsonString(rendered)
+			}
+
+			errorpath.Values = append(errorpath.Values, str)
+		}
+
+		if structured {
+			errorpath.RawValues = raw
 		}
 
 		eps = append(eps, errorpath)
@@ -57,96 +141,560 @@ func (e Error) JSON() map[string]any {
 
 	ret["Path"] = eps
 
+	if trunc.Dropped() {
+		ret["Truncated"] = trunc
+	}
+
 	return ret
 }
 
 // SummarizeConsole prepares a console friendly version of the error suitable for
-// printing.
+// printing to stdout, in the current layout and colored as ThemeFor
+// decides for stdout.  It is only wrapped if the layout sets a width.
+// WriteConsole decides both from the writer it is given.
 func (e Error) SummarizeConsole() string {
+	return e.SummarizeConsoleWith(ConsoleOptions{Theme: summaryTheme(), Layout: CurrentLayout()})
+}
+
+// WriteConsole writes SummarizeConsole output to w, colored and wrapped
+// if w is a terminal (see ConsoleOptionsFor).
+func (e Error) WriteConsole(w io.Writer) error {
+	_, err := io.WriteString(w, e.SummarizeConsoleWith(ConsoleOptionsFor(w)))
+
+	return err // nolint: wrapcheck
+}
+
+// SummarizeConsoleTheme is SummarizeConsole with an explicit theme and
+// no layout.
+func (e Error) SummarizeConsoleTheme(t Theme) string {
+	return e.SummarizeConsoleWith(ConsoleOptions{Theme: t})
+}
+
+// SummarizeConsoleWith is SummarizeConsole with explicit options.  No
+// width is detected: a Layout.Width of zero does not wrap.
+func (e Error) SummarizeConsoleWith(opts ConsoleOptions) string {
+	t := opts.Theme
+	l := newConsoleLayout(opts)
 	msg := e.LastMessage()
 	path := e.Path()
-	sum := fmt.Sprintf("%s %s\n",
-		c.Red.Sprint(`!! --Error--------------------------- !!
+	lim := CurrentLimits()
+	trunc := Truncation{}
+	sum := l.line(fmt.Sprintf("%s %s\n",
+		t.Banner.Sprint(`!! --Error--------------------------- !!
 - err:`),
-		c.White.Sprint(msg))
+		t.Message.Sprint(msg)))
+	sum += l.line(fmt.Sprintf("%s %s %s\n", t.Banner.Sprint("- ->"), t.Label.Sprint("id:"), t.Trace.Sprint(e.ID())))
 
-	octx := e.OriginContextString()
-	if octx != "" {
-		sum += fmt.Sprintf("%s %s\n", c.Red.Sprint("- ->"), c.Green.Sprint(octx))
+	for _, f := range preparedContextFields(outputConsole, e.contextFields, &trunc) {
+		sum += l.line(fmt.Sprintf("%s %s %s\n", t.Banner.Sprint("- ->"), t.Label.Sprintf("%s:", f.Name), t.Field.Sprint(f.Value)))
 	}
 
+	if cs, ok := e.ContextState(); ok && (cs.Done() || cs.HasDeadline) {
+		col := t.Field
+		if cs.Done() {
+			col = t.Warning
+		}
+
+		sum += l.line(fmt.Sprintf("%s %s %s\n", t.Banner.Sprint("- ->"), t.Label.Sprint("context:"), col.Sprint(cs)))
+	}
+
+	if tc, ok := e.Trace(); ok {
+		sum += l.line(fmt.Sprintf("%s %s %s\n", t.Banner.Sprint("- ->"), t.Label.Sprint("trace:"), t.Trace.Sprint(tc)))
+	}
+
+	frames := l.frames(path)
+
 	for i, pathe := range path {
-		col := c.Orange
+		col := t.Frame
 		if i == (len(path) - 1) {
-			col = c.Yellow
+			col = t.LastFrame
+		}
+
+		if l.AlignFrames {
+			sum += l.line(fmt.Sprintf("%s %s %s -> %s\n",
+				t.Banner.Sprint("- ->"),
+				col.Sprint(frames[i]),
+				col.Sprint(pathe.FuncName),
+				t.Message.Sprint(pathe.Msg),
+			))
+		} else {
+			sum += l.line(fmt.Sprintf("%s %s:%s/%s -> %s\n",
+				t.Banner.Sprint("- ->"),
+				col.Sprint(pathe.FileName),
+				col.Sprintf("%d", pathe.LineNumber),
+				col.Sprint(pathe.FuncName),
+				t.Message.Sprint(pathe.Msg),
+			))
 		}
 
-		sum += fmt.Sprintf("%s %s:%s/%s -> %s\n",
-			c.Red.Sprint("- ->"),
-			col.Sprint(pathe.FileName),
-			col.Sprintf("%d", pathe.LineNumber),
-			col.Sprint(pathe.FuncName),
-			c.White.Sprint(pathe.Msg),
-		)
+		if l.SourceLines > 0 {
+			sum += l.snippet(sourceSnippet(pathe.FilePath, pathe.LineNumber, l.SourceLines, t))
+		}
 
 		vals := pathe.Values()
-		for _, val := range vals {
-			switch valV := val.(type) {
-			case V:
-				switch valV.K {
-				case "db_error":
-					sum += fmt.Sprintf("%s %s %s: %s\n",
-						c.Red.Sprint("-"),
-						c.Magenta.Sprint("--$"),
-						c.WhiteOnCyan.Sprint(" DB Error "),
-						c.White.Sprint(valV.I))
-				case "io_error":
-					sum += fmt.Sprintf("%s %s %s: %s\n",
-						c.Red.Sprint("-"),
-						c.Magenta.Sprint("--$"),
-						c.WhiteOnRed.Sprint(" IO Error "),
-						c.White.Sprint(valV.I))
-				case "json":
-					str, convOK := valV.I.(string)
-					if convOK {
-						sum += fmt.Sprintf("%s %s %s: %s\n",
-							c.Red.Sprint("-"),
-							c.Magenta.Sprint("--$"),
-							c.WhiteOnGreen.Sprint(" json "),
-							c.SimpleColorString("json", str))
-					}
-				case "sql":
-					str, convOK := valV.I.(string)
-					if convOK {
-						sum += fmt.Sprintf("%s %s %s: %s\n",
-							c.Red.Sprint("-"),
-							c.Magenta.Sprint("--$"),
-							c.WhiteOnBlue.Sprint(" sql "),
-							c.SimpleColorString("sql", str))
-					}
-				case "validation":
-					sum += fmt.Sprintf("%s %s %s: %s\n",
-						c.Red.Sprint("-"),
-						c.Magenta.Sprint("--$"),
-						c.BlackOnYellow.Sprint(" validation "),
-						c.White.Sprint(valV.I))
-				default:
-					sum += fmt.Sprintf("%s %s %s => %s",
-						c.Red.Sprint("-"),
-						c.Magenta.Sprint("--$"),
-						c.WhiteOnMagenta.Sprintf(" %s ", valV.K),
-						c.SdumpColorSimple(valV.I))
-				}
-			default:
-				sum += fmt.Sprintf("%s %s %s",
-					c.Red.Sprint("-"),
-					c.Magenta.Sprint("--$"),
-					c.SdumpColorSimple(val))
+		for j, val := range vals {
+			if lim.MaxValues > 0 && j >= lim.MaxValues {
+				trunc.Values += len(vals) - j
+				sum += t.valueMarker() + t.Label.Sprintf(valuesMarker, len(vals)-j) + "\n"
+
+				break
+			}
+
+			str := consoleValue(prepareValue(outputConsole, val, lim.MaxDepth, &trunc), t)
+			if limited := limitValue(str, lim.MaxValueBytes, &trunc); limited != str {
+				str = limited + "\n"
 			}
+
+			sum += l.value(str)
 		}
 	}
 
-	sum += c.Red.Sprint("!! -----------------------------Error-- !!\n")
+	if limited, dropped := truncateRendered(sum, lim.MaxReportBytes); dropped > 0 {
+		sum = limited + "\n" + t.Label.Sprintf(bytesMarker, dropped) + "\n"
+	}
+
+	sum += t.Banner.Sprint("!! -----------------------------Error-- !!\n")
 
 	return sum
 }
+
+// HTTPBodyLimit caps how much of a body is included when rendering an
+// *http.Request or *http.Response.
+//
+// Rendering never reads a body that cannot be replayed: that could block
+// on the network, and errors are often rendered on another goroutine
+// than the one that owns the request.  Request bodies are shown if they
+// can be replayed with GetBody; other bodies only if the value was
+// attached through SnapshotRequest or SnapshotResponse.
+var HTTPBodyLimit = 4 << 10
+
+// Bytes renders a byte slice as a hex and ASCII dump.  Values of type
+// []byte are rendered this way automatically.
+type Bytes []byte
+
+func (b Bytes) RenderConsole() string {
+	return b.renderConsole(summaryTheme())
+}
+
+func (b Bytes) renderConsole(t Theme) string {
+	return fmt.Sprintf("%s\n%s", t.Label.Sprintf("(%d bytes)", len(b)), t.Data.Sprint(hex.Dump(b)))
+}
+
+func (b Bytes) RenderJSON() any {
+	return map[string]any{"Len": len(b), "Hex": hex.EncodeToString(b)}
+}
+
+// Diff renders a unified diff between an expected and an actual value.
+type Diff struct {
+	Expected any
+	Actual   any
+}
+
+func (d Diff) RenderConsole() string {
+	return d.renderConsole(summaryTheme())
+}
+
+func (d Diff) renderConsole(t Theme) string {
+	lines := d.lines()
+	out := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		switch line[0] {
+		case '-':
+			out = append(out, t.Removed.Sprint(line))
+		case '+':
+			out = append(out, t.Added.Sprint(line))
+		case '@':
+			out = append(out, t.Hunk.Sprint(line))
+		default:
+			out = append(out, line)
+		}
+	}
+
+	return "\n" + strings.Join(out, "\n") + "\n"
+}
+
+func (d Diff) RenderJSON() any {
+	return strings.Join(d.lines(), "\n")
+}
+
+func (d Diff) lines() []string {
+	exp := strings.Split(strings.TrimSuffix(c.Sdump(d.Expected), "\n"), "\n")
+	act := strings.Split(strings.TrimSuffix(c.Sdump(d.Actual), "\n"), "\n")
+
+	return append([]string{"--- expected", "+++ actual"}, unifiedDiff(exp, act)...)
+}
+
+// httpDump is the rendered, redacted form of an *http.Request or
+// *http.Response.
+type httpDump struct {
+	Start         string
+	Header        map[string][]string
+	Body          string `json:",omitempty"`
+	BodyTruncated int    `json:",omitempty"`
+	// BodyCut is set when the body was cut at HTTPBodyLimit but its
+	// length is unknown, so BodyTruncated cannot be counted.
+	BodyCut bool `json:",omitempty"`
+	// BodyNotRead is set when the body could not be shown without
+	// reading it (see HTTPBodyLimit).
+	BodyNotRead bool `json:",omitempty"`
+}
+
+func (h httpDump) RenderConsole() string {
+	return h.renderConsole(summaryTheme())
+}
+
+func (h httpDump) renderConsole(t Theme) string {
+	var sb strings.Builder
+
+	sb.WriteString(h.Start + "\n")
+
+	keys := make([]string, 0, len(h.Header))
+	for k := range h.Header {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range h.Header[k] {
+			sb.WriteString(k + ": " + v + "\n")
+		}
+	}
+
+	if h.Body != "" {
+		sb.WriteString("\n" + h.Body + "\n")
+	}
+
+	ret := "\n" + sb.String()
+	if t.Highlight {
+		ret = "\n" + c.SimpleColorString("http", sb.String())
+	}
+
+	switch {
+	case h.BodyTruncated > 0:
+		ret += t.Label.Sprintf(bytesMarker, h.BodyTruncated) + "\n"
+	case h.BodyCut:
+		ret += t.Label.Sprintf(bodyCutMarker, HTTPBodyLimit) + "\n"
+	case h.BodyNotRead:
+		ret += t.Label.Sprint(bodyNotReadMarker) + "\n"
+	}
+
+	return ret
+}
+
+func (h httpDump) RenderJSON() any { return h }
+
+func dumpHeader(hdr http.Header, redact bool) map[string][]string {
+	ret := make(map[string][]string, len(hdr))
+
+	for k, vals := range hdr {
+		if redact && IsRedactedKey(k) {
+			ret[k] = []string{RedactedText}
+
+			continue
+		}
+
+		ret[k] = append([]string(nil), vals...)
+	}
+
+	return ret
+}
+
+func dumpRequest(req *http.Request, redact bool) httpDump {
+	dump := httpDump{Header: dumpHeader(req.Header, redact)}
+
+	target := req.RequestURI
+	if req.URL != nil {
+		u := *req.URL
+		if redact {
+			u.RawQuery = redactQuery(u.RawQuery)
+		}
+
+		target = u.Redacted()
+	}
+
+	dump.Start = fmt.Sprintf("%s %s %s", req.Method, target, req.Proto)
+
+	if req.Host != "" {
+		dump.Header["Host"] = []string{req.Host}
+	}
+
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			dump.Body, dump.BodyTruncated = readBody(body, -1)
+			body.Close()
+		}
+
+		return dump
+	}
+
+	dump.addBody(req.Body)
+
+	return dump
+}
+
+func dumpResponse(resp *http.Response, redact bool) httpDump {
+	dump := httpDump{
+		Start:  fmt.Sprintf("%s %s", resp.Proto, resp.Status),
+		Header: dumpHeader(resp.Header, redact),
+	}
+
+	dump.addBody(resp.Body)
+
+	return dump
+}
+
+// addBody shows a body captured by a snapshot, and notes any other body
+// as not read.
+func (h *httpDump) addBody(body io.ReadCloser) {
+	switch bodyV := body.(type) {
+	case nil:
+	case *bodySnapshot:
+		h.Body, h.BodyTruncated, h.BodyCut = bodyV.cut()
+	default:
+		h.BodyNotRead = body != http.NoBody
+	}
+}
+
+// redactQuery replaces the values of query parameters with sensitive
+// names, leaving the rest of the query as it was.
+func redactQuery(raw string) string {
+	if raw == "" {
+		return raw
+	}
+
+	parts := strings.Split(raw, "&")
+
+	for i, part := range parts {
+		key, _, _ := strings.Cut(part, "=")
+		if name, err := url.QueryUnescape(key); err == nil && IsRedactedKey(name) {
+			parts[i] = key + "=" + RedactedText
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// readBody reads up to HTTPBodyLimit bytes of body, returning them and
+// how many bytes were left out.  The rest of body is read to count them
+// unless size, the known length of the body, is given.
+func readBody(body io.Reader, size int64) (string, int) {
+	buf, err := io.ReadAll(io.LimitReader(body, int64(HTTPBodyLimit)+1))
+	if err != nil || len(buf) <= HTTPBodyLimit {
+		return string(buf), 0
+	}
+
+	if size < 0 {
+		rest, _ := io.Copy(io.Discard, body)
+		size = int64(len(buf)) + rest
+	}
+
+	return string(buf[:HTTPBodyLimit]), int(size) - HTTPBodyLimit
+}
+
+// bodySnapshot is the start of a body captured by SnapshotRequest or
+// SnapshotResponse: up to one byte more than HTTPBodyLimit, so that
+// rendering can tell whether the body was cut.
+type bodySnapshot struct {
+	*bytes.Reader
+	buf  []byte
+	size int64 // the length of the whole body, or -1 if unknown
+}
+
+func (*bodySnapshot) Close() error { return nil }
+
+// cut returns the body to show, how many bytes were left out and
+// whether some were left out without the number being known.
+func (b *bodySnapshot) cut() (string, int, bool) {
+	if len(b.buf) <= HTTPBodyLimit {
+		return string(b.buf), 0, false
+	}
+
+	if b.size > int64(HTTPBodyLimit) {
+		return string(b.buf[:HTTPBodyLimit]), int(b.size) - HTTPBodyLimit, false
+	}
+
+	return string(b.buf[:HTTPBodyLimit]), 0, true
+}
+
+// snapshotBody reads the start of body and returns it as a snapshot,
+// along with a replacement for body that yields the whole body again.
+func snapshotBody(body io.ReadCloser, size int64) (*bodySnapshot, io.ReadCloser) {
+	buf, _ := io.ReadAll(io.LimitReader(body, int64(HTTPBodyLimit)+1))
+	restored := struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), body), body}
+
+	return &bodySnapshot{Reader: bytes.NewReader(buf), buf: buf, size: size}, restored
+}
+
+// SnapshotRequest returns a copy of req to attach as a value, holding
+// the start of its body so that it can be rendered later without
+// reading req.Body.  req.Body is replaced so that it still yields the
+// whole body.  It must be called by the goroutine that owns req, and may
+// block reading the body.  Requests whose body can be replayed with
+// GetBody are returned as they are.
+func SnapshotRequest(req *http.Request) *http.Request {
+	if req == nil || req.GetBody != nil || req.Body == nil || req.Body == http.NoBody {
+		return req
+	}
+
+	cp := req.Clone(req.Context())
+
+	var snap *bodySnapshot
+	snap, req.Body = snapshotBody(req.Body, req.ContentLength)
+	cp.Body = snap
+
+	return cp
+}
+
+// SnapshotResponse is SnapshotRequest for responses.
+func SnapshotResponse(resp *http.Response) *http.Response {
+	if resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+		return resp
+	}
+
+	cp := *resp
+	cp.Header = resp.Header.Clone()
+
+	var snap *bodySnapshot
+	snap, resp.Body = snapshotBody(resp.Body, resp.ContentLength)
+	cp.Body = snap
+
+	return &cp
+}
+
+const diffContext = 3
+
+// unifiedDiff returns the lines of a unified diff (without file headers)
+// between a and b.
+func unifiedDiff(a, b []string) []string {
+	ops := diffOps(a, b)
+	ret := []string{}
+
+	for start := 0; start < len(ops); {
+		// Find the next change.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+
+		if start == len(ops) {
+			break
+		}
+
+		from := start - diffContext
+		if from < 0 {
+			from = 0
+		}
+
+		// Extend the hunk while changes are within 2*context of each other.
+		end, quiet := start, 0
+		for end < len(ops) && quiet <= 2*diffContext {
+			if ops[end].kind == ' ' {
+				quiet++
+			} else {
+				quiet = 0
+			}
+			end++
+		}
+
+		end -= quiet
+		if end+diffContext < len(ops) {
+			end += diffContext
+		} else {
+			end = len(ops)
+		}
+
+		ret = append(ret, hunkHeader(ops[from:end]))
+		for _, op := range ops[from:end] {
+			ret = append(ret, string(op.kind)+op.line)
+		}
+
+		start = end
+	}
+
+	return ret
+}
+
+type diffOp struct {
+	kind   byte
+	line   string
+	aIndex int
+	bIndex int
+}
+
+func hunkHeader(ops []diffOp) string {
+	aStart, bStart, aLen, bLen := ops[0].aIndex+1, ops[0].bIndex+1, 0, 0
+
+	for _, op := range ops {
+		if op.kind != '+' {
+			aLen++
+		}
+
+		if op.kind != '-' {
+			bLen++
+		}
+	}
+
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", aStart, aLen, bStart, bLen)
+}
+
+// maxDiffCells bounds the LCS table; larger inputs are shown as a full
+// replacement.
+const maxDiffCells = 1 << 20
+
+// diffOps computes a line diff of a and b using the longest common
+// subsequence.
+func diffOps(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for i, line := range a {
+			ops = append(ops, diffOp{kind: '-', line: line, aIndex: i})
+		}
+
+		for j, line := range b {
+			ops = append(ops, diffOp{kind: '+', line: line, aIndex: len(a), bIndex: j})
+		}
+
+		return ops
+	}
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', line: a[i], aIndex: i, bIndex: j})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, diffOp{kind: '+', line: b[j], aIndex: i, bIndex: j})
+			j++
+		default:
+			ops = append(ops, diffOp{kind: '-', line: a[i], aIndex: i, bIndex: j})
+			i++
+		}
+	}
+
+	return ops
+}
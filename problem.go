@@ -0,0 +1,71 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HTTPStatuser is implemented by error classes that map to an HTTP
+// status other than 500.
+type HTTPStatuser interface {
+	HTTPStatus() int
+}
+
+// HTTPStatus returns the HTTP status for errors of class ec.
+func HTTPStatus(ec ErrorClass) int {
+	if s, ok := ec.(HTTPStatuser); ok {
+		return s.HTTPStatus()
+	}
+
+	return http.StatusInternalServerError
+}
+
+// ProblemContentType is the media type of a Problem (RFC 9457).
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details document describing an error
+// to an HTTP client.  It never includes the internal messages, path or
+// values of the error, only its public information (see PublicInfo); ID
+// lets support find the rest in the logs.
+type Problem struct {
+	Type   string `json:"type,omitempty"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Hint   string `json:"hint,omitempty"`
+	ID     string `json:"id"`
+}
+
+// Problem returns the problem details for the error.
+func (e Error) Problem() Problem {
+	status := HTTPStatus(e.Class())
+
+	title := http.StatusText(status)
+	if title == "" {
+		title = e.Class().What()
+	}
+
+	pub := e.Public()
+
+	return Problem{
+		Type:   pub.DocURL,
+		Title:  title,
+		Status: status,
+		Detail: pub.Message,
+		Hint:   pub.Hint,
+		ID:     e.ID(),
+	}
+}
+
+// WriteProblem writes err to w as an application/problem+json response.
+func WriteProblem(w http.ResponseWriter, err Error) {
+	p := err.Problem()
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+
+	_ = json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,144 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import "sync"
+
+// PublicInfo is the part of an error that is safe to show to end users.
+// The path messages and values are for developers and stay in the logs.
+type PublicInfo struct {
+	// Message is a short, user-safe description of what went wrong.
+	Message string
+	// Hint optionally tells the user what they can do about it.
+	Hint string
+	// DocURL optionally points to documentation about the error.
+	DocURL string
+	// MessageID optionally names the catalog template used to localize
+	// the message (see Catalog).
+	MessageID string
+}
+
+// DefaultPublicMessage is shown for errors whose class has no public
+// message.
+const DefaultPublicMessage = "An unexpected error occurred."
+
+type classID struct {
+	area   string
+	number uint32
+}
+
+func classIDOf(ec ErrorClass) classID {
+	return classID{area: ec.Area(), number: ec.Number()}
+}
+
+var (
+	publicMu     sync.RWMutex
+	classPublics = map[classID]PublicInfo{
+		classIDOf(NotFoundError{}):    {Message: "The requested item could not be found."},
+		classIDOf(NetworkError{}):     {Message: "A service we depend on could not be reached.", Hint: "Please try again later."},
+		classIDOf(NetworkTempError{}): {Message: "A service is temporarily unavailable.", Hint: "Please try again later."},
+		classIDOf(APIError{}):         {Message: "A service we depend on returned an error."},
+		classIDOf(ValidationError{}):  {Message: "The request was not valid."},
+		classIDOf(StateError{}):       {Message: "The request conflicts with the current state."},
+		classIDOf(CanceledError{}):    {Message: "The request was cancelled."},
+		classIDOf(TimeoutError{}):     {Message: "The request took too long to complete.", Hint: "Please try again later."},
+		classIDOf(CircuitOpenError{}): {Message: "A service is temporarily unavailable.", Hint: "Please try again later."},
+	}
+)
+
+// SetClassPublic sets the public information shown for errors of class
+// T that have none of their own, replacing any built-in default.
+func SetClassPublic[T ErrorClass](info PublicInfo) {
+	var ec T
+
+	publicMu.Lock()
+	defer publicMu.Unlock()
+
+	classPublics[classIDOf(ec)] = info
+}
+
+// ClassPublic returns the public information for errors of class ec.
+func ClassPublic(ec ErrorClass) PublicInfo {
+	publicMu.RLock()
+	info, ok := classPublics[classIDOf(ec)]
+	publicMu.RUnlock()
+
+	if !ok || info.Message == "" {
+		info.Message = DefaultPublicMessage
+	}
+
+	return info
+}
+
+// WithPublic attaches user-safe information to the error.  Empty fields
+// fall back to the class defaults.
+func (e Error) WithPublic(info PublicInfo) Error {
+	e.public = &info
+
+	return e
+}
+
+// WithPublicMessage attaches a user-safe message to the error.
+func (e Error) WithPublicMessage(msg string) Error {
+	return e.WithPublic(PublicInfo{Message: msg})
+}
+
+// WithMessageID sets the catalog ID used to localize the error's public
+// message, keeping any other public information.
+func (e Error) WithMessageID(id string) Error {
+	info := PublicInfo{}
+	if e.public != nil {
+		info = *e.public
+	}
+
+	info.MessageID = id
+
+	return e.WithPublic(info)
+}
+
+// Public returns the user-safe information for the error: the first set
+// on it or on an error it wraps, with any empty fields taken from the
+// class default.
+func (e Error) Public() PublicInfo {
+	if e == nil {
+		return PublicInfo{}
+	}
+
+	def := ClassPublic(e.Class())
+
+	if info, ok := e.ownPublic(); ok {
+		if info.Message == "" {
+			info.Message = def.Message
+		}
+
+		if info.Hint == "" {
+			info.Hint = def.Hint
+		}
+
+		if info.DocURL == "" {
+			info.DocURL = def.DocURL
+		}
+
+		return info
+	}
+
+	return def
+}
+
+// ownPublic returns the public information set on the error or the
+// first error it wraps that has any, ignoring class defaults.
+func (e Error) ownPublic() (PublicInfo, bool) {
+	for _, eData := range errorChain(e) {
+		if eData.public != nil {
+			return *eData.public, true
+		}
+	}
+
+	return PublicInfo{}, false
+}
+
+// PublicMessage returns the user-safe message for the error, never one
+// of its internal path messages.
+func (e Error) PublicMessage() string {
+	return e.Public().Message
+}
@@ -0,0 +1,67 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type publicTestError struct{}
+
+func (publicTestError) What() string   { return "publicTestError" }
+func (publicTestError) Area() string   { return "test" }
+func (publicTestError) Number() uint32 { return 4501 }
+
+func TestPublic(t *testing.T) {
+	Convey("Verify public and internal messages.", t, func() {
+		Convey("check class defaults", func() {
+			err := e.New[e.DataError]("row 42 of accounts failed checksum")
+			So(err.PublicMessage(), ShouldEqual, e.DefaultPublicMessage)
+			So(e.New[e.TimeoutError]("slow").Public(), ShouldResemble, e.PublicInfo{
+				Message: "The request took too long to complete.",
+				Hint:    "Please try again later.",
+			})
+			So(e.Error(nil).PublicMessage(), ShouldBeEmpty)
+		})
+		Convey("check class public information can be set", func() {
+			e.SetClassPublic[publicTestError](e.PublicInfo{Message: "Test failed.", DocURL: "https://example.com/errors/test"})
+			err := e.New[publicTestError]("internal detail")
+			So(err.Public(), ShouldResemble, e.PublicInfo{Message: "Test failed.", DocURL: "https://example.com/errors/test"})
+		})
+		Convey("check per error public information", func() {
+			err := e.New[e.TimeoutError]("db query exceeded 30s").WithPublicMessage("Your report is taking longer than usual.")
+			So(err.Public(), ShouldResemble, e.PublicInfo{
+				Message: "Your report is taking longer than usual.",
+				Hint:    "Please try again later.",
+			})
+
+			wrapped := e.WrapError[e.DataError](err)
+			So(wrapped.PublicMessage(), ShouldEqual, "Your report is taking longer than usual.")
+
+			err.WithPublic(e.PublicInfo{Hint: "Try a smaller date range."})
+			So(err.Public().Message, ShouldEqual, "The request took too long to complete.")
+			So(err.Public().Hint, ShouldEqual, "Try a smaller date range.")
+		})
+		Convey("check HTTP rendering only shows public information", func() {
+			err := e.New[e.ValidationError]("field ssn=123-45-6789 malformed").WithPublic(e.PublicInfo{
+				Message: "Please check the highlighted fields.",
+				Hint:    "Social security numbers look like 123-45-6789.",
+				DocURL:  "https://example.com/errors/validation",
+			})
+			rec := httptest.NewRecorder()
+			e.WriteProblem(rec, err)
+			So(rec.Code, ShouldEqual, 400)
+			So(rec.Body.String(), ShouldNotContainSubstring, "malformed")
+			var p e.Problem
+			So(json.Unmarshal(rec.Body.Bytes(), &p), ShouldBeNil)
+			So(p.Detail, ShouldEqual, "Please check the highlighted fields.")
+			So(p.Type, ShouldEqual, "https://example.com/errors/validation")
+			So(err.LastMessage(), ShouldEqual, "field ssn=123-45-6789 malformed")
+		})
+	})
+}
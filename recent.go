@@ -0,0 +1,231 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecentEntry is one distinct error (by fingerprint) held by Recent.
+type RecentEntry struct {
+	Fingerprint string
+	// Error is the latest occurrence.
+	Error ReportedError
+	Count int
+	First time.Time
+	Last  time.Time
+}
+
+// RecentConfig configures a Recent store.
+type RecentConfig struct {
+	// Size is the number of distinct errors kept.  Defaults to 100.
+	Size int
+	// Clock is the time source; the system clock if nil.
+	Clock Clock
+}
+
+// Recent keeps the most recently seen distinct errors in memory, for
+// instance to serve them on a debug page.  It is a Sink, so it can be
+// fed by a Reporter, or errors can be added directly.
+type Recent struct {
+	cfg     RecentConfig
+	mu      sync.Mutex
+	entries []*RecentEntry // most recently seen first
+}
+
+// NewRecent returns an empty store.
+func NewRecent(cfg RecentConfig) *Recent {
+	if cfg.Size <= 0 {
+		cfg.Size = 100
+	}
+
+	cfg.Clock = clockOrSystem(cfg.Clock)
+
+	return &Recent{cfg: cfg}
+}
+
+// Add records an occurrence of err.
+func (r *Recent) Add(err Error) {
+	if err == nil {
+		return
+	}
+
+	r.add(newReportedError(err))
+}
+
+func (r *Recent) add(rep ReportedError) {
+	fp := rep.Fingerprint
+	now := r.cfg.Clock.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := &RecentEntry{Fingerprint: fp, First: now}
+
+	for i, existing := range r.entries {
+		if existing.Fingerprint == fp {
+			entry = existing
+			r.entries = append(r.entries[:i], r.entries[i+1:]...)
+
+			break
+		}
+	}
+
+	entry.Error = rep
+	entry.Count++
+	entry.Last = now
+
+	r.entries = append([]*RecentEntry{entry}, r.entries...)
+	if len(r.entries) > r.cfg.Size {
+		r.entries = r.entries[:r.cfg.Size]
+	}
+}
+
+// Write adds a batch of reported errors.
+func (r *Recent) Write(_ context.Context, batch []ReportedError) error {
+	for _, rep := range batch {
+		r.add(rep)
+	}
+
+	return nil
+}
+
+// Entries returns a copy of the entries, most recently seen first.
+func (r *Recent) Entries() []RecentEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ret := make([]RecentEntry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		ret = append(ret, *entry)
+	}
+
+	return ret
+}
+
+// recentView is the rendered (and redacted) form of an entry.
+type recentView struct {
+	Fingerprint string
+	ID          string
+	Class       string
+	Message     string
+	Public      string
+	Count       int
+	First       time.Time
+	Last        time.Time
+	Error       map[string]any
+}
+
+type recentPathView struct {
+	Caller string
+	Values []string
+}
+
+func (v recentView) Path() []recentPathView {
+	eps, _ := v.Error["Path"].([]ErrorPathJSON)
+	ret := make([]recentPathView, 0, len(eps))
+
+	for i := len(eps) - 1; i >= 0; i-- {
+		pv := recentPathView{Caller: eps[i].Caller}
+
+		for j, str := range eps[i].Values {
+			if j < len(eps[i].RawValues) {
+				if _, ok := eps[i].RawValues[j].(string); !ok {
+					if buf, err := json.MarshalIndent(eps[i].RawValues[j], "", "  "); err == nil {
+						str = string(buf)
+					}
+				}
+			}
+
+			pv.Values = append(pv.Values, strings.TrimSpace(str))
+		}
+
+		ret = append(ret, pv)
+	}
+
+	return ret
+}
+
+func (r *Recent) views() []recentView {
+	entries := r.Entries()
+	ret := make([]recentView, 0, len(entries))
+
+	for _, entry := range entries {
+		ret = append(ret, recentView{
+			Fingerprint: entry.Fingerprint,
+			ID:          entry.Error.ID,
+			Class:       entry.Error.Class.What(),
+			Message:     entry.Error.Message,
+			Public:      entry.Error.Public,
+			Count:       entry.Count,
+			First:       entry.First,
+			Last:        entry.Last,
+			Error:       entry.Error.JSON,
+		})
+	}
+
+	return ret
+}
+
+var recentTemplate = template.Must(template.New("recent").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>/debug/errors</title>
+<style>
+body { font-family: sans-serif; font-size: 90%; }
+table { border-collapse: collapse; }
+td, th { padding: 0.2em 0.6em; text-align: left; vertical-align: top; border-bottom: 1px solid #ddd; }
+pre { margin: 0.2em 0 0.2em 1.5em; }
+.class { font-weight: bold; }
+</style>
+</head>
+<body>
+<p>/debug/errors — {{len .}} distinct recent errors (<a href="?format=json">json</a>)</p>
+<table>
+<tr><th>Count</th><th>Last seen</th><th>First seen</th><th>Class</th><th>Error</th></tr>
+{{range .}}<tr>
+<td>{{.Count}}</td>
+<td>{{.Last.Format "2006-01-02 15:04:05.000"}}</td>
+<td>{{.First.Format "2006-01-02 15:04:05.000"}}</td>
+<td class="class">{{.Class}}</td>
+<td><details><summary>{{.Message}}</summary>
+<div>public message “{{.Public}}”</div>
+<div>fingerprint {{.Fingerprint}}, latest id {{.ID}}</div>
+{{range .Path}}<details open><summary>{{.Caller}}</summary>
+{{range .Values}}<pre>{{.}}</pre>
+{{end}}</details>
+{{end}}</details></td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// Handler serves the recent errors as an HTML page, in the spirit of
+// /debug/pprof, or as JSON when requested with ?format=json or an
+// Accept header of application/json.  Values are rendered through JSON
+// and so honor the JSON redaction policy.
+func (r *Recent) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		views := r.views()
+
+		if req.URL.Query().Get("format") == "json" || strings.Contains(req.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(views)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = recentTemplate.Execute(w, views)
+	})
+}
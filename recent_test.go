@@ -0,0 +1,81 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecent(t *testing.T) {
+	t.Parallel()
+	Convey("Verify the recent error store and debug page.", t, func() {
+		clk := newFakeClock()
+		start := clk.Now()
+		r := e.NewRecent(e.RecentConfig{Size: 2, Clock: clk})
+
+		Convey("check counts per fingerprint and eviction", func() {
+			r.Add(fBad())
+			clk.Advance(time.Second)
+			r.Add(fBadNested())
+			clk.Advance(time.Second)
+			r.Add(fBad())
+			entries := r.Entries()
+			So(len(entries), ShouldEqual, 2)
+			So(entries[0].Count, ShouldEqual, 2)
+			So(entries[0].First, ShouldEqual, start)
+			So(entries[0].Last, ShouldEqual, start.Add(2*time.Second))
+			So(entries[1].Last, ShouldEqual, start.Add(time.Second))
+			So(entries[0].Error.Message, ShouldEqual, "oops")
+			So(entries[1].Count, ShouldEqual, 1)
+			r.Add(fBadValues())
+			entries = r.Entries()
+			So(len(entries), ShouldEqual, 2)
+			So(entries[0].Error.Message, ShouldEqual, "moo")
+			So(entries[1].Error.Message, ShouldEqual, "oops")
+		})
+		Convey("check it can be fed by a reporter", func() {
+			rep := e.NewReporter(e.ReporterConfig{Sinks: []e.Sink{r}})
+			rep.Report(fBad())
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			So(rep.Close(ctx), ShouldBeNil)
+			So(len(r.Entries()), ShouldEqual, 1)
+		})
+		Convey("check the html and json pages", func() {
+			r.Add(fRedacted())
+			r.Add(fBadNested3())
+			h := r.Handler()
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/errors", nil))
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(rec.Header().Get("Content-Type"), ShouldStartWith, "text/html")
+			body := rec.Body.String()
+			So(body, ShouldContainSubstring, "<details>")
+			So(body, ShouldContainSubstring, "top level error string")
+			So(body, ShouldContainSubstring, "ducks")
+			So(body, ShouldNotContainSubstring, "hunter2")
+			So(body, ShouldNotContainSubstring, fBadNested3().Error())
+			So(body, ShouldContainSubstring, e.DefaultPublicMessage)
+			So(body, ShouldContainSubstring, "2024-01-02 03:04:05.000")
+
+			rec = httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/errors?format=json", nil))
+			So(rec.Header().Get("Content-Type"), ShouldEqual, "application/json")
+			So(rec.Body.String(), ShouldNotContainSubstring, "hunter2")
+			var views []map[string]any
+			So(json.Unmarshal(rec.Body.Bytes(), &views), ShouldBeNil)
+			So(len(views), ShouldEqual, 2)
+			So(views[0]["Class"], ShouldEqual, "UnknownError")
+			So(views[1]["Class"], ShouldEqual, "ValidationError")
+		})
+	})
+}
@@ -0,0 +1,368 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RedactedText replaces any value that has been redacted from output.
+const RedactedText = "[REDACTED]"
+
+// redactTag is the struct tag value that marks a field as sensitive,
+// e.g. `e:"redact"`.
+const redactTag = "redact"
+
+// Secret wraps a value that should never appear in error output.  It
+// prints as RedactedText through fmt, encoding/json and the error
+// renderers unless the output policy allows revealing it.
+type Secret[T any] struct {
+	val T
+}
+
+// NewSecret wraps val so that it is redacted from error output.
+func NewSecret[T any](val T) Secret[T] {
+	return Secret[T]{val: val}
+}
+
+// Reveal returns the wrapped value.
+func (s Secret[T]) Reveal() T { return s.val }
+
+func (Secret[T]) String() string   { return RedactedText }
+func (Secret[T]) GoString() string { return RedactedText }
+
+func (Secret[T]) Format(f fmt.State, _ rune) { _, _ = f.Write([]byte(RedactedText)) }
+
+func (Secret[T]) MarshalJSON() ([]byte, error) { return json.Marshal(RedactedText) }
+
+func (s Secret[T]) revealAny() any { return s.val }
+
+// secret lets the redactor recognise any instantiation of Secret.
+type secret interface {
+	revealAny() any
+}
+
+var secretType = reflect.TypeOf((*secret)(nil)).Elem()
+
+// RedactionPolicy decides which outputs have sensitive values scrubbed.
+// JSON output is usually shipped off the machine and should always be
+// redacted; console output may be revealed for local debugging.
+type RedactionPolicy struct {
+	Console bool
+	JSON    bool
+}
+
+type outputKind int
+
+const (
+	outputConsole outputKind = iota
+	outputJSON
+	// outputPublic is text shown to end users, which is always redacted.
+	outputPublic
+)
+
+var defaultRedactedKeys = []string{
+	`passw(or)?d`,
+	`secret`,
+	`token`,
+	`authorization`,
+	`api[-_]?key`,
+	`cookie`,
+}
+
+type redactionConfig struct {
+	mu     sync.RWMutex
+	policy RedactionPolicy
+	keys   []*regexp.Regexp
+}
+
+var redaction = &redactionConfig{
+	policy: RedactionPolicy{Console: true, JSON: true},
+	keys:   defaultKeyPatterns(),
+}
+
+func defaultKeyPatterns() []*regexp.Regexp {
+	keys := make([]*regexp.Regexp, 0, len(defaultRedactedKeys))
+	for _, k := range defaultRedactedKeys {
+		keys = append(keys, regexp.MustCompile(`(?i)`+k))
+	}
+
+	return keys
+}
+
+// SetRedactionPolicy sets which outputs are redacted.  The default
+// redacts both console and JSON output.
+func SetRedactionPolicy(policy RedactionPolicy) {
+	redaction.mu.Lock()
+	defer redaction.mu.Unlock()
+
+	redaction.policy = policy
+}
+
+// AddRedactedKeys adds (case insensitive) regular expressions for value
+// keys, map keys and struct field names whose values are redacted.
+func AddRedactedKeys(patterns ...string) error {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, p := range patterns {
+		re, err := regexp.Compile(`(?i)` + p)
+		if err != nil {
+			return err
+		}
+
+		res = append(res, re)
+	}
+
+	redaction.mu.Lock()
+	defer redaction.mu.Unlock()
+
+	redaction.keys = append(redaction.keys, res...)
+
+	return nil
+}
+
+// ResetRedactedKeys drops the patterns added with AddRedactedKeys,
+// leaving only the built-in ones.
+func ResetRedactedKeys() {
+	redaction.mu.Lock()
+	defer redaction.mu.Unlock()
+
+	redaction.keys = defaultKeyPatterns()
+}
+
+func (rc *redactionConfig) enabled(out outputKind) bool {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	switch out {
+	case outputJSON:
+		return rc.policy.JSON
+	case outputPublic:
+		return true
+	case outputConsole:
+	}
+
+	return rc.policy.Console
+}
+
+// IsRedactedKey reports whether values stored under key are redacted.
+func IsRedactedKey(key string) bool {
+	redaction.mu.RLock()
+	defer redaction.mu.RUnlock()
+
+	for _, re := range redaction.keys {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// prepareValue returns a copy of val that is safe to render for out,
+// with sensitive values redacted and nesting cut off at maxDepth (zero
+// for no limit).  The number of values cut off is added to trunc.
+func prepareValue(out outputKind, val Value, maxDepth int, trunc *Truncation) Value {
+	s := sanitizer{
+		redact:   redaction.enabled(out),
+		maxDepth: maxDepth,
+		seen:     map[uintptr]bool{},
+		trunc:    trunc,
+	}
+	ret, _ := s.value(reflect.ValueOf(val), 0)
+
+	switch retV := ret.(type) {
+	case []byte:
+		return Bytes(retV)
+	case V:
+		if b, ok := retV.I.([]byte); ok {
+			return V{K: retV.K, I: Bytes(b)}
+		}
+	}
+
+	return ret
+}
+
+// sanitizer walks values before they are rendered.  Values are only
+// copied when something inside them changes; the copy of a struct or
+// map is a map[string]any of its fields.
+type sanitizer struct {
+	redact   bool
+	maxDepth int
+	seen     map[uintptr]bool
+	trunc    *Truncation
+}
+
+// nolint: cyclop,gocognit
+func (s sanitizer) value(rv reflect.Value, depth int) (any, bool) {
+	if !rv.IsValid() {
+		return nil, false
+	}
+
+	if rv.CanInterface() {
+		switch val := rv.Interface().(type) {
+		case secret:
+			if s.redact {
+				return RedactedText, true
+			}
+
+			return val.revealAny(), true
+		case *http.Request:
+			if val != nil {
+				return dumpRequest(val, s.redact), true
+			}
+		case *http.Response:
+			if val != nil {
+				return dumpResponse(val, s.redact), true
+			}
+		case Diff:
+			exp, _ := s.value(reflect.ValueOf(val.Expected), depth+1)
+			act, _ := s.value(reflect.ValueOf(val.Actual), depth+1)
+
+			return Diff{Expected: exp, Actual: act}, true
+		case V:
+			if s.redact && IsRedactedKey(val.K) {
+				return V{K: val.K, I: RedactedText}, true
+			}
+
+			inner, changed := s.value(reflect.ValueOf(val.I), depth)
+			if changed {
+				return V{K: val.K, I: inner}, true
+			}
+
+			return val, false
+		}
+	} else if s.redact && rv.Type().Implements(secretType) {
+		// An unexported Secret field cannot be reached through Interface.
+		return RedactedText, true
+	}
+
+	if s.maxDepth > 0 && depth >= s.maxDepth && isContainer(rv) {
+		s.trunc.Depth++
+
+		return fmt.Sprintf(depthMarker, s.maxDepth), true
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		return s.value(rv.Elem(), depth)
+	case reflect.Pointer:
+		if rv.IsNil() || s.seen[rv.Pointer()] {
+			return s.orig(rv), false
+		}
+
+		s.seen[rv.Pointer()] = true
+		defer delete(s.seen, rv.Pointer())
+
+		inner, changed := s.value(rv.Elem(), depth)
+		if changed {
+			return inner, true
+		}
+	case reflect.Struct:
+		return s.structValue(rv, depth)
+	case reflect.Map:
+		return s.mapValue(rv, depth)
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+
+		elems := make([]any, rv.Len())
+		changed := false
+
+		for i := 0; i < rv.Len(); i++ {
+			var c bool
+			elems[i], c = s.value(rv.Index(i), depth+1)
+			changed = changed || c
+		}
+
+		if changed {
+			return elems, true
+		}
+	}
+
+	return s.orig(rv), false
+}
+
+func (sanitizer) orig(rv reflect.Value) any {
+	if rv.CanInterface() {
+		return rv.Interface()
+	}
+
+	return fmt.Sprintf("%v", rv)
+}
+
+func (s sanitizer) structValue(rv reflect.Value, depth int) (any, bool) {
+	typ := rv.Type()
+	fields := make(map[string]any, rv.NumField())
+	changed := false
+
+	// Unexported fields are checked too: the dumpers print them, so a
+	// private password field would otherwise leak.
+	for i := 0; i < rv.NumField(); i++ {
+		field := typ.Field(i)
+
+		if s.redact && (hasRedactTag(field) || IsRedactedKey(field.Name)) {
+			fields[field.Name] = RedactedText
+			changed = true
+
+			continue
+		}
+
+		var c bool
+		fields[field.Name], c = s.value(rv.Field(i), depth+1)
+		changed = changed || c
+	}
+
+	if changed {
+		return fields, true
+	}
+
+	return s.orig(rv), false
+}
+
+func (s sanitizer) mapValue(rv reflect.Value, depth int) (any, bool) {
+	if rv.IsNil() {
+		return s.orig(rv), false
+	}
+
+	entries := make(map[string]any, rv.Len())
+	changed := false
+	iter := rv.MapRange()
+
+	for iter.Next() {
+		key := fmt.Sprint(iter.Key())
+		if s.redact && iter.Key().Kind() == reflect.String && IsRedactedKey(key) {
+			entries[key] = RedactedText
+			changed = true
+
+			continue
+		}
+
+		var c bool
+		entries[key], c = s.value(iter.Value(), depth+1)
+		changed = changed || c
+	}
+
+	if changed {
+		return entries, true
+	}
+
+	return s.orig(rv), false
+}
+
+func hasRedactTag(field reflect.StructField) bool {
+	for _, opt := range strings.Split(field.Tag.Get("e"), ",") {
+		if opt == redactTag {
+			return true
+		}
+	}
+
+	return false
+}
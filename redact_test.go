@@ -0,0 +1,90 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type loginRequest struct {
+	User     string
+	Password string
+	Pin      string `e:"redact"`
+	Session  e.Secret[string]
+}
+
+func fRedacted() e.Error {
+	return e.NewWithVals[e.ValidationError]("login failed", func() e.Values {
+		return e.Values{
+			e.V{K: "request", I: loginRequest{User: "bob", Password: "hunter2", Pin: "8812", Session: e.NewSecret("sess-abc")}},
+			e.V{K: "Authorization", I: "Bearer xyzzy"},
+			e.V{K: "headers", I: map[string]string{"X-Api-Key": "k-123", "Accept": "json"}},
+		}
+	})
+}
+
+func TestRedaction(t *testing.T) {
+	Convey("Verify that sensitive values are redacted from output.", t, func() {
+		Convey("check secrets do not leak through fmt or json", func() {
+			s := e.NewSecret("hunter2")
+			So(fmt.Sprintf("%v %s %+v %#v", s, s, s, s), ShouldNotContainSubstring, "hunter2")
+			js, err := json.Marshal(s)
+			So(err, ShouldBeNil)
+			So(string(js), ShouldEqual, `"[REDACTED]"`)
+			So(s.Reveal(), ShouldEqual, "hunter2")
+		})
+		Convey("check json and console output are scrubbed", func() {
+			err := fRedacted()
+			js := fmt.Sprint(err.JSON()["Path"])
+			con := err.SummarizeConsole()
+			for _, out := range []string{js, con} {
+				So(out, ShouldContainSubstring, "bob")
+				So(out, ShouldContainSubstring, "json")
+				for _, leak := range []string{"hunter2", "8812", "sess-abc", "xyzzy", "k-123"} {
+					So(out, ShouldNotContainSubstring, leak)
+				}
+			}
+			So(strings.Count(js, e.RedactedText), ShouldEqual, 5)
+		})
+		Convey("check console output can reveal values while json stays scrubbed", func() {
+			e.SetRedactionPolicy(e.RedactionPolicy{Console: false, JSON: true})
+			defer e.SetRedactionPolicy(e.RedactionPolicy{Console: true, JSON: true})
+			err := fRedacted()
+			So(err.SummarizeConsole(), ShouldContainSubstring, "sess-abc")
+			So(fmt.Sprint(err.JSON()["Path"]), ShouldNotContainSubstring, "sess-abc")
+		})
+		Convey("check unexported fields are scrubbed", func() {
+			type creds struct {
+				user     string
+				password string
+				nested   struct{ token string }
+				session  e.Secret[string]
+			}
+			c := creds{user: "alice", password: "hunter2", session: e.NewSecret("sess-42")}
+			c.nested.token = "tok-99"
+			err := e.New[e.DataError]("private").AddValue("creds", c)
+			for _, out := range []string{err.SummarizeConsole(), fmt.Sprint(err.JSON()["Path"])} {
+				So(out, ShouldContainSubstring, "alice")
+				So(out, ShouldNotContainSubstring, "hunter2")
+				So(out, ShouldNotContainSubstring, "tok-99")
+				So(out, ShouldNotContainSubstring, "sess-42")
+			}
+		})
+		Convey("check custom key patterns", func() {
+			defer e.ResetRedactedKeys()
+			So(e.IsRedactedKey("ssn"), ShouldBeFalse)
+			So(e.AddRedactedKeys(`^ssn$`), ShouldBeNil)
+			So(e.IsRedactedKey("SSN"), ShouldBeTrue)
+			So(e.AddRedactedKeys(`(`), ShouldNotBeNil)
+			e.ResetRedactedKeys()
+			So(e.IsRedactedKey("ssn"), ShouldBeFalse)
+			So(e.IsRedactedKey("password"), ShouldBeTrue)
+		})
+	})
+}
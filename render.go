@@ -0,0 +1,203 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	c "github.com/paudley/colorout"
+)
+
+// ConsoleRenderer is implemented by values that know how to render
+// themselves for SummarizeConsole.
+type ConsoleRenderer interface {
+	RenderConsole() string
+}
+
+// themedRenderer is implemented by the built-in value kinds, which
+// style their console output with the theme in use.
+type themedRenderer interface {
+	renderConsole(t Theme) string
+}
+
+// JSONRenderer is implemented by values that know how to render
+// themselves for JSON.  The result must be encodable by encoding/json.
+type JSONRenderer interface {
+	RenderJSON() any
+}
+
+// Renderer renders values stored under a particular V key.  Either
+// function may be nil, in which case that output falls back to the
+// default rendering.
+type Renderer struct {
+	// Console returns the text shown after the value marker, typically
+	// a label followed by the value.
+	Console func(v V) string
+	// ConsoleTheme is Console for renderers that style their output
+	// with the theme in use.  It takes precedence over Console.
+	ConsoleTheme func(v V, t Theme) string
+	// JSON returns the value to encode in place of v.I.
+	JSON func(v V) any
+}
+
+var (
+	renderersMu sync.RWMutex
+	renderers   = map[string]Renderer{
+		"db_error": {
+			ConsoleTheme: labelRenderer("db_error", " DB Error "),
+			JSON:         sprintJSON,
+		},
+		"io_error": {
+			ConsoleTheme: labelRenderer("io_error", " IO Error "),
+			JSON:         sprintJSON,
+		},
+		"json": {
+			ConsoleTheme: highlightRenderer("json", " json ", "json"),
+			JSON:         rawJSON,
+		},
+		"sql": {
+			ConsoleTheme: highlightRenderer("sql", " sql ", "sql"),
+			JSON:         sprintJSON,
+		},
+		"validation": {
+			ConsoleTheme: labelRenderer("validation", " validation "),
+			JSON:         sprintJSON,
+		},
+	}
+)
+
+// RegisterRenderer sets the renderer used for values with key k,
+// replacing any existing renderer including the built-in ones.
+func RegisterRenderer(k string, r Renderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+
+	renderers[k] = r
+}
+
+// UnregisterRenderer removes the renderer for key k.
+func UnregisterRenderer(k string) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+
+	delete(renderers, k)
+}
+
+// LookupRenderer returns the renderer registered for key k.
+func LookupRenderer(k string) (Renderer, bool) {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+
+	r, ok := renderers[k]
+
+	return r, ok
+}
+
+func labelRenderer(key, label string) func(V, Theme) string {
+	return func(v V, t Theme) string {
+		return fmt.Sprintf("%s: %s\n", t.badge(key).Sprint(label), t.Message.Sprint(v.I))
+	}
+}
+
+func highlightRenderer(key, label, language string) func(V, Theme) string {
+	return func(v V, t Theme) string {
+		str, convOK := v.I.(string)
+		if !convOK {
+			return ""
+		}
+
+		if t.Highlight {
+			str = c.SimpleColorString(language, str)
+		}
+
+		return fmt.Sprintf("%s: %s\n", t.badge(key).Sprint(label), str)
+	}
+}
+
+func sprintJSON(v V) any {
+	return fmt.Sprint(v.I)
+}
+
+func rawJSON(v V) any {
+	str, convOK := v.I.(string)
+	if convOK && json.Valid([]byte(str)) {
+		return json.RawMessage(str)
+	}
+
+	return v.I
+}
+
+// consoleValue renders a single value for SummarizeConsole.
+func consoleValue(val Value, t Theme) string {
+	var body string
+
+	// plain drops any escapes the value produced itself when the theme
+	// does not want them.
+	plain := func(str string) string {
+		if t.Highlight {
+			return str
+		}
+
+		return stripANSI(str)
+	}
+
+	switch valV := val.(type) {
+	case V:
+		if r, ok := LookupRenderer(valV.K); ok && (r.ConsoleTheme != nil || r.Console != nil) {
+			if r.ConsoleTheme != nil {
+				body = r.ConsoleTheme(valV, t)
+			} else {
+				body = plain(r.Console(valV))
+			}
+
+			if body == "" {
+				return ""
+			}
+
+			break
+		}
+
+		label := t.Key.Sprintf(" %s ", valV.K)
+		if tr, ok := valV.I.(themedRenderer); ok {
+			body = label + ": " + tr.renderConsole(t)
+		} else if cr, ok := valV.I.(ConsoleRenderer); ok {
+			body = label + ": " + plain(cr.RenderConsole())
+		} else {
+			body = label + " => " + plain(c.SdumpColorSimple(valV.I))
+		}
+	case themedRenderer:
+		body = valV.renderConsole(t)
+	case ConsoleRenderer:
+		body = plain(valV.RenderConsole())
+	default:
+		body = plain(c.SdumpColorSimple(val))
+	}
+
+	if !strings.HasSuffix(body, "\n") {
+		body += "\n"
+	}
+
+	return t.valueMarker() + body
+}
+
+// jsonValue renders a single value for JSON.  Values without a
+// renderer are dumped to a string.
+func jsonValue(val Value) any {
+	switch valV := val.(type) {
+	case V:
+		if r, ok := LookupRenderer(valV.K); ok && r.JSON != nil {
+			return V{K: valV.K, I: r.JSON(valV)}
+		}
+
+		if jr, ok := valV.I.(JSONRenderer); ok {
+			return V{K: valV.K, I: jr.RenderJSON()}
+		}
+	case JSONRenderer:
+		return valV.RenderJSON()
+	}
+
+	return c.Sdump(val)
+}
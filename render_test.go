@@ -0,0 +1,63 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type point struct{ X, Y int }
+
+func (p point) RenderConsole() string { return "POINT<" + strings.Repeat("*", p.X) + ">" }
+func (p point) RenderJSON() any       { return []int{p.X, p.Y} }
+
+func fRendered() e.Error {
+	return e.NewWithVals[e.DataError]("render me", func() e.Values {
+		return e.Values{
+			e.V{K: "yaml", I: "a: 1"},
+			e.V{K: "where", I: point{X: 3, Y: 4}},
+			e.V{K: "json", I: `{"a":1}`},
+		}
+	})
+}
+
+func TestRenderers(t *testing.T) {
+	Convey("Verify that value renderers are used for output.", t, func() {
+		e.RegisterRenderer("yaml", e.Renderer{
+			Console: func(v e.V) string { return "YAML:" + v.I.(string) },
+			JSON:    func(v e.V) any { return map[string]int{"a": 1} },
+		})
+		defer e.UnregisterRenderer("yaml")
+
+		Convey("check console rendering", func() {
+			sum := fRendered().SummarizeConsole()
+			So(sum, ShouldContainSubstring, "YAML:a: 1\n")
+			So(sum, ShouldContainSubstring, "POINT<***>")
+		})
+		Convey("check json rendering", func() {
+			js, err := json.Marshal(fRendered().JSON()["Path"])
+			So(err, ShouldBeNil)
+			So(string(js), ShouldContainSubstring, `{"K":"yaml","I":{"a":1}}`)
+			So(string(js), ShouldContainSubstring, `{"K":"where","I":[3,4]}`)
+			So(string(js), ShouldContainSubstring, `{"K":"json","I":{"a":1}}`)
+		})
+		Convey("check values keep their string form", func() {
+			eps := fRendered().JSON()["Path"].([]e.ErrorPathJSON)
+			So(eps[0].Values, ShouldContain, `{"K":"yaml","I":{"a":1}}`)
+			So(len(eps[0].RawValues), ShouldEqual, len(eps[0].Values))
+			So(eps[0].RawValues[0], ShouldResemble, e.V{K: "yaml", I: map[string]int{"a": 1}})
+		})
+		Convey("check built-ins can be overridden", func() {
+			orig, ok := e.LookupRenderer("json")
+			So(ok, ShouldBeTrue)
+			e.RegisterRenderer("json", e.Renderer{Console: func(v e.V) string { return "raw json" }})
+			defer e.RegisterRenderer("json", orig)
+			So(fRendered().SummarizeConsole(), ShouldContainSubstring, "raw json\n")
+		})
+	})
+}
@@ -0,0 +1,416 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReportedError is an error as it was when reported.  It is rendered on
+// the reporting goroutine, so sinks never touch the live error, which
+// its owner may still be wrapping or adding values to.
+type ReportedError struct {
+	ID          string
+	Fingerprint string
+	Class       ErrorClass
+	// Message is the last message of the error.
+	Message string
+	// Public is the message safe to show end users (see PublicMessage).
+	Public string
+	// JSON is the error's JSON() form.
+	JSON map[string]any
+}
+
+func newReportedError(err Error) ReportedError {
+	return ReportedError{
+		ID:          err.ID(),
+		Fingerprint: err.Fingerprint(),
+		Class:       err.Class(),
+		Message:     err.LastMessage(),
+		Public:      err.PublicMessage(),
+		JSON:        err.JSON(),
+	}
+}
+
+// Sink receives batches of reported errors from a Reporter.  Write is
+// only ever called from the reporter's worker goroutine, with a context
+// that ends when the write times out or the reporter gives up closing.
+type Sink interface {
+	Write(ctx context.Context, batch []ReportedError) error
+}
+
+// marshalBatch encodes each error's JSON form on its own line.
+func marshalBatch(batch []ReportedError) []byte {
+	var buf bytes.Buffer
+
+	for _, rep := range batch {
+		line, jerr := json.Marshal(rep.JSON)
+		if jerr != nil {
+			line, _ = json.Marshal(map[string]any{"Kind": "errorBacktrace", "Message": rep.Message, "EncodeError": jerr.Error()})
+		}
+
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes()
+}
+
+// WriterSink writes each error as a line of JSON to an io.Writer.
+type WriterSink struct {
+	W io.Writer
+}
+
+func (s WriterSink) Write(_ context.Context, batch []ReportedError) error {
+	_, err := s.W.Write(marshalBatch(batch))
+
+	return err
+}
+
+// FileSink appends errors as lines of JSON to a file.
+type FileSink struct {
+	WriterSink
+	f *os.File
+}
+
+// NewFileSink opens (creating if needed) path for appending.
+func NewFileSink(path string) (*FileSink, Error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) // nolint: gosec
+	if err != nil {
+		return nil, WrapError[FileError](err).AddValue("path", path)
+	}
+
+	return &FileSink{WriterSink: WriterSink{W: f}, f: f}, nil
+}
+
+// Close closes the underlying file.
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
+
+// defaultSinkClient is used by HTTPSinks without a client.  Its timeout
+// keeps one hung collector from stalling the reporter.
+var defaultSinkClient = &http.Client{Timeout: 30 * time.Second}
+
+// HTTPSink POSTs each batch as newline delimited JSON to an endpoint.
+type HTTPSink struct {
+	URL    string
+	Header http.Header
+	// Client is a client with a 30 second timeout if nil.
+	Client *http.Client
+}
+
+func (s HTTPSink) Write(ctx context.Context, batch []ReportedError) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(marshalBatch(batch)))
+	if err != nil {
+		return WrapError[APIError](err)
+	}
+
+	for k, vals := range s.Header {
+		req.Header[k] = vals
+	}
+
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	client := s.Client
+	if client == nil {
+		client = defaultSinkClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return WrapError[UnknownError](err)
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return NewWithVals[APIError]("error sink rejected batch", func() Values {
+			return Values{V{K: "url", I: s.URL}, V{K: "status", I: resp.Status}}
+		})
+	}
+
+	return nil
+}
+
+// ReporterConfig configures a Reporter.
+type ReporterConfig struct {
+	Sinks []Sink
+	// QueueSize bounds the queue; errors reported while it is full are
+	// dropped.  Defaults to 1024.
+	QueueSize int
+	// BatchSize is the most errors handed to a sink at once.  Defaults
+	// to 100.
+	BatchSize int
+	// FlushInterval is the longest a partial batch waits.  Defaults to
+	// one second.
+	FlushInterval time.Duration
+	// WriteTimeout bounds each sink write.  Defaults to 30 seconds.
+	WriteTimeout time.Duration
+	// SampleRates keeps only a fraction (0 to 1) of the errors of a
+	// class; classes not listed are always kept.
+	SampleRates map[ErrorClass]float64
+	// Rand returns numbers in [0, 1) for sampling; math/rand if nil.
+	Rand func() float64
+	// OnSinkError is called when a sink fails to write a batch.
+	OnSinkError func(sink Sink, err error)
+}
+
+// ReporterStats counts what has happened to reported errors.
+type ReporterStats struct {
+	Reported   uint64
+	SampledOut uint64
+	Dropped    uint64
+	Written    uint64
+	SinkErrors uint64
+}
+
+// Reporter pushes reported errors through a bounded queue to its sinks
+// on a background goroutine, batching writes.
+type Reporter struct {
+	cfg     ReporterConfig
+	queue   chan ReportedError
+	flushes chan chan struct{}
+	done    chan struct{}
+	// ctx is given to sink writes and cancelled once Close returns.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// mu guards closed, so nothing is queued once Close has started.
+	mu     sync.RWMutex
+	closed bool
+	randMu sync.Mutex
+
+	reported, sampledOut, dropped, written, sinkErrors atomic.Uint64
+}
+
+// NewReporter starts a reporter.  Call Close to stop it.
+func NewReporter(cfg ReporterConfig) *Reporter {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 30 * time.Second
+	}
+
+	if cfg.Rand == nil {
+		cfg.Rand = rand.Float64 // nolint: gosec
+	}
+
+	r := &Reporter{
+		cfg:     cfg,
+		queue:   make(chan ReportedError, cfg.QueueSize),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+
+	go r.run()
+
+	return r
+}
+
+// Report renders err and queues it without blocking.  It returns false
+// if the error was sampled out, dropped because the queue is full, or
+// the reporter is closed.
+func (r *Reporter) Report(err Error) bool {
+	if err == nil {
+		return false
+	}
+
+	r.reported.Add(1)
+	runHooks(HookReport, err, err.path[len(err.path)-1])
+
+	if !r.sample(err.Class()) {
+		r.sampledOut.Add(1)
+
+		return false
+	}
+
+	rep := newReportedError(err)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		r.dropped.Add(1)
+
+		return false
+	}
+
+	select {
+	case r.queue <- rep:
+		return true
+	default:
+		r.dropped.Add(1)
+
+		return false
+	}
+}
+
+func (r *Reporter) sample(ec ErrorClass) bool {
+	rate, ok := r.cfg.SampleRates[ec]
+	if !ok || rate >= 1 {
+		return true
+	}
+
+	r.randMu.Lock()
+	defer r.randMu.Unlock()
+
+	return r.cfg.Rand() < rate
+}
+
+// Flush waits until everything queued so far has been written.
+func (r *Reporter) Flush(ctx context.Context) Error {
+	ack := make(chan struct{})
+
+	select {
+	case r.flushes <- ack:
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return WrapErrorCtx[UnknownError](ctx, ctx.Err())
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return WrapErrorCtx[UnknownError](ctx, ctx.Err())
+	}
+}
+
+// Close flushes the queue and stops the reporter.  Errors reported
+// afterwards are dropped.  If ctx ends first, the write in progress is
+// cancelled, whatever is still queued is dropped and the context error
+// is returned; the reporter is stopped either way.
+func (r *Reporter) Close(ctx context.Context) Error {
+	r.mu.Lock()
+	closed := r.closed
+	r.closed = true
+	r.mu.Unlock()
+
+	if closed {
+		return nil
+	}
+
+	defer r.cancel()
+	defer close(r.done)
+
+	return r.Flush(ctx)
+}
+
+// Stats returns the reporter's counters.
+func (r *Reporter) Stats() ReporterStats {
+	return ReporterStats{
+		Reported:   r.reported.Load(),
+		SampledOut: r.sampledOut.Load(),
+		Dropped:    r.dropped.Load(),
+		Written:    r.written.Load(),
+		SinkErrors: r.sinkErrors.Load(),
+	}
+}
+
+func (r *Reporter) run() {
+	ticker := time.NewTicker(r.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]ReportedError, 0, r.cfg.BatchSize)
+
+	for {
+		select {
+		case err := <-r.queue:
+			batch = append(batch, err)
+			if len(batch) >= r.cfg.BatchSize {
+				batch = r.write(batch)
+			}
+		case <-ticker.C:
+			batch = r.write(batch)
+		case ack := <-r.flushes:
+			batch = r.drain(batch)
+			close(ack)
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// drain writes the batch and everything currently queued.
+func (r *Reporter) drain(batch []ReportedError) []ReportedError {
+	for {
+		select {
+		case err := <-r.queue:
+			batch = append(batch, err)
+			if len(batch) >= r.cfg.BatchSize {
+				batch = r.write(batch)
+			}
+		default:
+			return r.write(batch)
+		}
+	}
+}
+
+func (r *Reporter) write(batch []ReportedError) []ReportedError {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	for _, sink := range r.cfg.Sinks {
+		if err := r.writeSink(sink, batch); err != nil {
+			r.sinkErrors.Add(1)
+
+			if r.cfg.OnSinkError != nil {
+				r.cfg.OnSinkError(sink, err)
+			}
+		}
+	}
+
+	r.written.Add(uint64(len(batch)))
+
+	// Sinks may hold on to the batch, so start a fresh one.
+	return make([]ReportedError, 0, r.cfg.BatchSize)
+}
+
+func (r *Reporter) writeSink(sink Sink, batch []ReportedError) error {
+	ctx, cancel := context.WithTimeout(r.ctx, r.cfg.WriteTimeout)
+	defer cancel()
+
+	return sink.Write(ctx, batch)
+}
+
+var defaultReporter atomic.Pointer[Reporter]
+
+// SetReporter sets the reporter used by Report.  Pass nil to disable
+// reporting.
+func SetReporter(r *Reporter) {
+	defaultReporter.Store(r)
+}
+
+// Report sends err to the reporter set with SetReporter, returning false
+// if there is none or the error was not queued.
+func Report(err Error) bool {
+	r := defaultReporter.Load()
+	if r == nil {
+		return false
+	}
+
+	return r.Report(err)
+}
@@ -0,0 +1,178 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type blockingSink struct {
+	release chan struct{}
+	mu      sync.Mutex
+	batches [][]e.ReportedError
+}
+
+func (s *blockingSink) Write(_ context.Context, batch []e.ReportedError) error {
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+// hungSink never finishes a write until its context ends.
+type hungSink struct {
+	cancelled chan error
+}
+
+func (s hungSink) Write(ctx context.Context, _ []e.ReportedError) error {
+	<-ctx.Done()
+	s.cancelled <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestReporter(t *testing.T) {
+	t.Parallel()
+	Convey("Verify the error reporter pipeline.", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		Convey("check errors are written as json lines", func() {
+			var buf bytes.Buffer
+			dir := t.TempDir()
+			fileSink, ferr := e.NewFileSink(filepath.Join(dir, "errors.log"))
+			So(ferr, ShouldBeNil)
+			defer fileSink.Close()
+			r := e.NewReporter(e.ReporterConfig{Sinks: []e.Sink{e.WriterSink{W: &buf}, fileSink}, BatchSize: 2})
+			So(r.Report(fBad()), ShouldBeTrue)
+			So(r.Report(fBadNested()), ShouldBeTrue)
+			So(r.Report(fBadValues()), ShouldBeTrue)
+			So(r.Report(nil), ShouldBeFalse)
+			So(r.Close(ctx), ShouldBeNil)
+			So(r.Report(fBad()), ShouldBeFalse)
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			So(len(lines), ShouldEqual, 3)
+			var first map[string]any
+			So(json.Unmarshal([]byte(lines[1]), &first), ShouldBeNil)
+			So(first["Message"], ShouldEqual, "second level oops")
+			fileData, rerr := os.ReadFile(filepath.Join(dir, "errors.log"))
+			So(rerr, ShouldBeNil)
+			So(string(fileData), ShouldEqual, buf.String())
+			st := r.Stats()
+			So(st.Written, ShouldEqual, 3)
+			So(st.Dropped, ShouldEqual, 1)
+		})
+		Convey("check per class sampling", func() {
+			n := 0
+			r := e.NewReporter(e.ReporterConfig{
+				SampleRates: map[e.ErrorClass]float64{e.ValidationError{}: 0.25},
+				Rand:        func() float64 { n++; return float64(n%4) / 4 },
+			})
+			kept := 0
+			for i := 0; i < 8; i++ {
+				if r.Report(e.New[e.ValidationError]("bad input")) {
+					kept++
+				}
+			}
+			So(kept, ShouldEqual, 2)
+			So(r.Report(e.New[e.DataError]("always")), ShouldBeTrue)
+			So(r.Close(ctx), ShouldBeNil)
+			So(r.Stats().SampledOut, ShouldEqual, 6)
+		})
+		Convey("check backpressure drops instead of blocking", func() {
+			sink := &blockingSink{release: make(chan struct{})}
+			r := e.NewReporter(e.ReporterConfig{Sinks: []e.Sink{sink}, QueueSize: 2, BatchSize: 1})
+			accepted := 0
+			for i := 0; i < 10; i++ {
+				if r.Report(fBad()) {
+					accepted++
+				}
+			}
+			So(accepted, ShouldBeLessThan, 10)
+			close(sink.release)
+			So(r.Close(ctx), ShouldBeNil)
+			st := r.Stats()
+			So(st.Dropped, ShouldEqual, 10-accepted)
+			So(st.Written, ShouldEqual, accepted)
+		})
+		Convey("check errors are rendered when reported", func() {
+			sink := &blockingSink{release: make(chan struct{})}
+			close(sink.release)
+			r := e.NewReporter(e.ReporterConfig{Sinks: []e.Sink{sink}})
+			err := fBad()
+			So(r.Report(err), ShouldBeTrue)
+			e.Wrap(err, "wrapped after reporting").AddValue("late", 1)
+			So(r.Close(ctx), ShouldBeNil)
+			So(len(sink.batches), ShouldEqual, 1)
+			rep := sink.batches[0][0]
+			So(rep.Message, ShouldEqual, "oops")
+			So(rep.JSON["Message"], ShouldEqual, "oops")
+			So(rep.ID, ShouldEqual, err.ID())
+			So(rep.Class, ShouldEqual, err.Class())
+		})
+		Convey("check close gives up on a hung sink", func() {
+			sink := hungSink{cancelled: make(chan error, 1)}
+			r := e.NewReporter(e.ReporterConfig{Sinks: []e.Sink{sink}, WriteTimeout: time.Hour})
+			So(r.Report(fBad()), ShouldBeTrue)
+			short, cancelShort := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancelShort()
+			So(r.Close(short), ShouldNotBeNil)
+			So(<-sink.cancelled, ShouldEqual, context.Canceled)
+			So(r.Close(ctx), ShouldBeNil)
+			So(r.Report(fBad()), ShouldBeFalse)
+		})
+		Convey("check sink writes time out", func() {
+			sink := hungSink{cancelled: make(chan error, 1)}
+			r := e.NewReporter(e.ReporterConfig{Sinks: []e.Sink{sink}, WriteTimeout: 10 * time.Millisecond})
+			So(r.Report(fBad()), ShouldBeTrue)
+			So(r.Flush(ctx), ShouldBeNil)
+			So(<-sink.cancelled, ShouldEqual, context.DeadlineExceeded)
+			So(r.Stats().SinkErrors, ShouldEqual, 1)
+			So(r.Close(ctx), ShouldBeNil)
+		})
+		Convey("check the http sink", func() {
+			var mu sync.Mutex
+			received := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sc := bufio.NewScanner(r.Body)
+				mu.Lock()
+				for sc.Scan() {
+					received++
+				}
+				mu.Unlock()
+				io.WriteString(w, "ok")
+			}))
+			defer srv.Close()
+			sinkErrs := 0
+			r := e.NewReporter(e.ReporterConfig{
+				Sinks:       []e.Sink{e.HTTPSink{URL: srv.URL}, e.HTTPSink{URL: srv.URL + "/\x7f"}},
+				OnSinkError: func(e.Sink, error) { sinkErrs++ },
+			})
+			e.SetReporter(r)
+			defer e.SetReporter(nil)
+			So(e.Report(fBad()), ShouldBeTrue)
+			So(r.Flush(ctx), ShouldBeNil)
+			mu.Lock()
+			So(received, ShouldEqual, 1)
+			mu.Unlock()
+			So(sinkErrs, ShouldEqual, 1)
+			So(r.Close(ctx), ShouldBeNil)
+		})
+	})
+}
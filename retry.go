@@ -0,0 +1,198 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Retryable is implemented by error classes that know whether a failure
+// of that class is worth retrying.
+type Retryable interface {
+	Retryable() bool
+}
+
+// IsRetryable reports whether errors of class ec should be retried.
+func IsRetryable(ec ErrorClass) bool {
+	r, ok := ec.(Retryable)
+
+	return ok && r.Retryable()
+}
+
+// RetryPolicy controls Retry.  Zero fields take their value from
+// DefaultRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of calls, including the first.
+	MaxAttempts int
+	// InitialBackoff is the wait before the second attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between attempts.
+	MaxBackoff time.Duration
+	// Multiplier grows the backoff after each attempt.
+	Multiplier float64
+	// Jitter randomizes each wait by up to ±Jitter of its length.
+	Jitter float64
+	// MaxElapsed gives up once the next attempt would start after this
+	// much time since the first.  Zero means no overall deadline.
+	MaxElapsed time.Duration
+	// RetryIf decides whether a failed attempt is retried.  By default
+	// errors whose class is Retryable are retried.
+	RetryIf func(err Error) bool
+	// Clock is the time source; the system clock if nil.
+	Clock Clock
+	// Rand returns numbers in [0, 1) for jitter; math/rand if nil.
+	Rand func() float64
+}
+
+// DefaultRetryPolicy is used for any zero field of a RetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultRetryPolicy.InitialBackoff
+	}
+
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+
+	if p.Multiplier <= 0 {
+		p.Multiplier = DefaultRetryPolicy.Multiplier
+	}
+
+	if p.RetryIf == nil {
+		p.RetryIf = func(err Error) bool { return IsRetryable(err.Class()) }
+	}
+
+	if p.Rand == nil {
+		p.Rand = rand.Float64 // nolint: gosec
+	}
+
+	p.Clock = clockOrSystem(p.Clock)
+
+	return p
+}
+
+// Backoff returns the wait after the given (1 based) failed attempt.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	p = p.withDefaults()
+
+	delay := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		delay *= 1 + p.Jitter*(2*p.Rand()-1)
+	}
+
+	return time.Duration(delay)
+}
+
+// notRetryable is the reason given when RetryIf rejects an error.
+const notRetryable = "not retryable"
+
+type retryAttempt struct {
+	err     Error
+	elapsed time.Duration
+	backoff time.Duration
+}
+
+// Retry calls fn until it succeeds, returns an error that should not be
+// retried, or the policy gives up.  The returned error is the last
+// attempt's error with a path element added for each earlier failed
+// attempt and one explaining why retrying stopped.  An error that should
+// not be retried is returned unchanged if it was the first.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) Error) Error {
+	policy = policy.withDefaults()
+	start := policy.Clock.Now()
+	attempts := []retryAttempt{}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		elapsed := policy.Clock.Now().Sub(start)
+
+		var reason string
+
+		switch {
+		case !policy.RetryIf(err):
+			reason = notRetryable
+		case attempt >= policy.MaxAttempts:
+			reason = "max attempts reached"
+		case ctx.Err() != nil:
+			reason = "context done"
+		}
+
+		backoff := policy.Backoff(attempt)
+		if reason == "" && policy.MaxElapsed > 0 && elapsed+backoff > policy.MaxElapsed {
+			reason = "max elapsed time reached"
+		}
+
+		if reason == "" {
+			select {
+			case <-ctx.Done():
+				reason = "context done"
+			case <-policy.Clock.After(backoff):
+			}
+		}
+
+		if reason == notRetryable && len(attempts) == 0 {
+			return err
+		}
+
+		if reason != "" {
+			return retryFailed(ctx, err, attempts, reason, elapsed)
+		}
+
+		attempts = append(attempts, retryAttempt{err: err, elapsed: elapsed, backoff: backoff})
+	}
+}
+
+// retryFailed records the earlier attempts and the reason for giving up
+// on the final error.
+func retryFailed(ctx context.Context, final Error, attempts []retryAttempt, reason string, elapsed time.Duration) Error {
+	for i, a := range attempts {
+		a := a
+		final = Wrap(final, fmt.Sprintf("attempt %d failed: %s", i+1, a.err.LastMessage())).AddValues(func() Values {
+			return Values{
+				V{K: "attempt_error", I: a.err.Error()},
+				V{K: "attempt_class", I: a.err.Class().What()},
+				V{K: "elapsed", I: a.elapsed.String()},
+				V{K: "backoff", I: a.backoff.String()},
+			}
+		})
+	}
+
+	attemptCount := len(attempts) + 1
+
+	final = Wrap(final, fmt.Sprintf("giving up after %d attempts: %s", attemptCount, reason)).AddValues(func() Values {
+		return Values{
+			V{K: "attempts", I: attemptCount},
+			V{K: "elapsed", I: elapsed.String()},
+		}
+	})
+
+	if ctx.Err() != nil && !final.originContextP {
+		final.attachContext(ctx)
+	}
+
+	return final
+}
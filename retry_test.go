@@ -0,0 +1,105 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func flaky(failures int, class func(msg string) e.Error) (func(context.Context) e.Error, *int) {
+	calls := 0
+
+	return func(context.Context) e.Error {
+		calls++
+		if calls <= failures {
+			return class("upstream unavailable")
+		}
+
+		return nil
+	}, &calls
+}
+
+func tempErr(msg string) e.Error  { return e.New[e.NetworkTempError](msg) }
+func validErr(msg string) e.Error { return e.New[e.ValidationError](msg) }
+
+func TestRetry(t *testing.T) {
+	t.Parallel()
+	Convey("Verify the class driven retry executor.", t, func() {
+		clk := newFakeClock()
+		policy := e.RetryPolicy{
+			MaxAttempts:    4,
+			InitialBackoff: time.Second,
+			MaxBackoff:     3 * time.Second,
+			Multiplier:     2,
+			Jitter:         0.5,
+			Clock:          clk,
+			Rand:           func() float64 { return 0.5 },
+		}
+
+		Convey("check transient errors are retried with backoff", func() {
+			fn, calls := flaky(3, tempErr)
+			So(e.Retry(context.Background(), policy, fn), ShouldBeNil)
+			So(*calls, ShouldEqual, 4)
+			So(clk.Waits(), ShouldResemble, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second})
+		})
+		Convey("check jitter", func() {
+			policy.Rand = func() float64 { return 0 }
+			So(policy.Backoff(1), ShouldEqual, 500*time.Millisecond)
+			policy.Rand = func() float64 { return 0.999999 }
+			So(policy.Backoff(2), ShouldAlmostEqual, 3*time.Second, float64(time.Millisecond))
+		})
+		Convey("check attempts are recorded when giving up", func() {
+			var wraps atomic.Int32
+			remove := e.AddHook(func(event e.HookEvent, _ e.Error, pe e.PathElement) {
+				if event == e.HookWrap && strings.HasPrefix(pe.FuncName, "github.com/paudley/e_test.TestRetry") {
+					wraps.Add(1)
+				}
+			})
+			defer remove()
+			fn, calls := flaky(10, tempErr)
+			err := e.Retry(context.Background(), policy, fn)
+			So(err, ShouldNotBeNil)
+			So(*calls, ShouldEqual, 4)
+			So(err.Class(), ShouldEqual, e.NetworkTempError{})
+			path := err.Path()
+			So(len(path), ShouldEqual, 5)
+			So(path[1].Msg, ShouldEqual, "attempt 1 failed: upstream unavailable")
+			So(path[1].FuncName, ShouldStartWith, "github.com/paudley/e_test.TestRetry")
+			So(path[4].Msg, ShouldEqual, "giving up after 4 attempts: max attempts reached")
+			So(wraps.Load(), ShouldEqual, 4)
+			backoff, ok := e.Lookup[string](err, "backoff")
+			So(ok, ShouldBeTrue)
+			So(backoff, ShouldEqual, "3s")
+		})
+		Convey("check non retryable classes stop immediately", func() {
+			fn, calls := flaky(10, validErr)
+			err := e.Retry(context.Background(), policy, fn)
+			So(*calls, ShouldEqual, 1)
+			So(err.LastMessage(), ShouldEqual, "upstream unavailable")
+			So(len(err.Path()), ShouldEqual, 1)
+			So(len(clk.Waits()), ShouldEqual, 0)
+		})
+		Convey("check the overall deadline", func() {
+			policy.MaxElapsed = 2500 * time.Millisecond
+			fn, calls := flaky(10, tempErr)
+			err := e.Retry(context.Background(), policy, fn)
+			So(*calls, ShouldEqual, 2)
+			So(err.LastMessage(), ShouldEqual, "giving up after 2 attempts: max elapsed time reached")
+		})
+		Convey("check context cancellation", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			fn, calls := flaky(10, tempErr)
+			err := e.Retry(ctx, policy, fn)
+			So(*calls, ShouldEqual, 1)
+			So(err.LastMessage(), ShouldEqual, "giving up after 1 attempts: context done")
+		})
+	})
+}
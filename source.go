@@ -0,0 +1,138 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	c "github.com/paudley/colorout"
+)
+
+const (
+	// maxSourceBytes skips source files too large to be worth showing.
+	maxSourceBytes = 1 << 20
+	// maxSourceFiles bounds the source cache.
+	maxSourceFiles = 64
+	// sourceTabWidth is the number of spaces a tab is expanded to.
+	sourceTabWidth = 4
+)
+
+// sourceCache holds the lines of source files read for snippets.  Files
+// that cannot be read are cached as nil so they are only tried once.
+var sourceCache = struct {
+	mu    sync.Mutex
+	files map[string][]string
+	order []string
+}{files: map[string][]string{}}
+
+// ClearSourceCache forgets the source files read for snippets, for
+// instance after they have been edited.
+func ClearSourceCache() {
+	sourceCache.mu.Lock()
+	defer sourceCache.mu.Unlock()
+
+	sourceCache.files = map[string][]string{}
+	sourceCache.order = nil
+}
+
+func sourceLines(path string) []string {
+	sourceCache.mu.Lock()
+	lines, ok := sourceCache.files[path]
+	sourceCache.mu.Unlock()
+
+	if ok {
+		return lines
+	}
+
+	// Read outside the lock so that renders of other errors do not wait
+	// on the disk.
+	if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() && fi.Size() <= maxSourceBytes {
+		if buf, err := os.ReadFile(path); err == nil {
+			lines = strings.Split(strings.ReplaceAll(string(buf), "\t", strings.Repeat(" ", sourceTabWidth)), "\n")
+		}
+	}
+
+	sourceCache.mu.Lock()
+	defer sourceCache.mu.Unlock()
+
+	// Another render may have read the file meanwhile.
+	if cached, ok := sourceCache.files[path]; ok {
+		return cached
+	}
+
+	if len(sourceCache.order) >= maxSourceFiles {
+		delete(sourceCache.files, sourceCache.order[0])
+		sourceCache.order = sourceCache.order[1:]
+	}
+
+	sourceCache.files[path] = lines
+	sourceCache.order = append(sourceCache.order, path)
+
+	return lines
+}
+
+// sourceLanguage picks the highlighter for a file from its extension.
+func sourceLanguage(path string) string {
+	if ext := strings.TrimPrefix(filepath.Ext(path), "."); ext != "" {
+		return ext
+	}
+
+	return "go"
+}
+
+// sourceSnippet renders around lines of source either side of line in
+// path, marking the line itself.  It is empty if the source cannot be
+// found.
+func sourceSnippet(path string, line, around int, t Theme) string {
+	lines := sourceLines(path)
+	if around <= 0 || line < 1 || line > len(lines) {
+		return ""
+	}
+
+	first, last := line-around, line+around
+	if first < 1 {
+		first = 1
+	}
+
+	if last > len(lines) {
+		last = len(lines)
+	}
+
+	code := lines[first-1 : last]
+	if t.Highlight {
+		// Highlight the snippet as a whole so multi-line tokens such
+		// as comments are colored correctly.
+		highlighted := strings.Split(c.SimpleColorString(sourceLanguage(path), strings.Join(code, "\n")+"\n"), "\n")
+		if n := len(highlighted); n > 0 && stripANSI(highlighted[n-1]) == "" {
+			highlighted = highlighted[:n-1]
+		}
+
+		if len(highlighted) == len(code) {
+			for i := range highlighted {
+				highlighted[i] += ansiReset
+			}
+
+			code = highlighted
+		}
+	}
+
+	width := len(fmt.Sprint(last))
+	sb := strings.Builder{}
+
+	for i, src := range code {
+		n := first + i
+
+		marker := "  "
+		if n == line {
+			marker = t.Banner.Sprint("▶ ")
+		}
+
+		fmt.Fprintf(&sb, "%s%s%s %s %s\n", valueIndent, marker, t.Label.Sprintf("%*d", width, n), t.Label.Sprint("│"), src)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func fSource() e.Error {
+	before := "line before"
+	return e.New[e.DataError]("snippet " + before) // the error line
+}
+
+func TestSource(t *testing.T) {
+	Convey("Verify source snippets in console output.", t, func() {
+		Convey("check the full path is kept", func() {
+			pe := fSource().Path()[0]
+			So(pe.FileName, ShouldEqual, "source_test.go")
+			So(filepath.IsAbs(pe.FilePath), ShouldBeTrue)
+			So(filepath.Base(pe.FilePath), ShouldEqual, "source_test.go")
+		})
+		Convey("check snippets are opt-in", func() {
+			So(fSource().SummarizeConsoleTheme(e.ThemeMonochrome), ShouldNotContainSubstring, "│")
+		})
+		Convey("check the source around the error is shown", func() {
+			err := fSource()
+			sum := err.SummarizeConsoleWith(e.ConsoleOptions{Theme: e.ThemeMonochrome, Layout: e.Layout{SourceLines: 1}})
+			line := err.Path()[0].LineNumber
+			So(sum, ShouldContainSubstring, "▶ "+strconv.Itoa(line)+" │     return e.New[e.DataError](\"snippet \" + before) // the error line\n")
+			So(sum, ShouldContainSubstring, "  "+strconv.Itoa(line-1)+" │     before := \"line before\"\n")
+			So(sum, ShouldContainSubstring, "  "+strconv.Itoa(line+1)+" │ }\n")
+			So(sum, ShouldNotContainSubstring, "func fSource")
+		})
+		Convey("check snippets are highlighted with color themes", func() {
+			sum := fSource().SummarizeConsoleWith(e.ConsoleOptions{Theme: e.ThemeDark, Layout: e.Layout{SourceLines: 2}})
+			So(sum, ShouldContainSubstring, e.ThemeDark.Banner.Sprint("▶ "))
+			So(sum, ShouldContainSubstring, "\x1b[38;2;")
+		})
+		Convey("check snippets fit the width", func() {
+			for _, th := range []e.Theme{e.ThemeMonochrome, e.ThemeDark} {
+				sum := fSource().SummarizeConsoleWith(e.ConsoleOptions{Theme: th, Layout: e.Layout{Width: 50, SourceLines: 2}})
+				plain := layoutANSIRe.ReplaceAllString(sum, "")
+				for _, line := range strings.Split(plain, "\n") {
+					So(utf8.RuneCountInString(line), ShouldBeLessThanOrEqualTo, 50)
+				}
+				So(plain, ShouldContainSubstring, "│     return e.New[e.DataError](")
+				So(plain, ShouldContainSubstring, "…\n")
+			}
+		})
+		Convey("check concurrent renders share the cache", func() {
+			e.ClearSourceCache()
+			var wg sync.WaitGroup
+			sums := make([]string, 8)
+			for i := range sums {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					sums[i] = fSource().SummarizeConsoleWith(e.ConsoleOptions{Theme: e.ThemeMonochrome, Layout: e.Layout{SourceLines: 1}})
+				}(i)
+			}
+			wg.Wait()
+			for _, sum := range sums {
+				So(sum, ShouldContainSubstring, "// the error line\n")
+			}
+		})
+		Convey("check missing source is skipped", func() {
+			err := e.New[e.DataError]("elsewhere")
+			err.Path()[0].FilePath = "/nonexistent/file.go"
+			e.ClearSourceCache()
+			So(err.SummarizeConsoleWith(e.ConsoleOptions{Theme: e.ThemeMonochrome, Layout: e.Layout{SourceLines: 2}}), ShouldNotContainSubstring, "│")
+		})
+	})
+}
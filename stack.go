@@ -26,6 +26,8 @@ type CallFrame struct {
 	File     string
 	Line     int
 	Function string
+	// Path is the full path of File as recorded in the binary.
+	Path string
 }
 
 const maxCallerLength = 20
@@ -53,16 +55,26 @@ func FilteredStack() ([]string, []CallFrame) {
 		switch frame.Function {
 		case "github.com/paudley/e.CallLocation":
 		case "github.com/paudley/e.New[...]":
+		case "github.com/paudley/e.newError[...]":
+		case "github.com/paudley/e.wrapError[...]":
 		case "github.com/paudley/e.Full[...]":
 		case "github.com/paudley/e.NewWithContext[...]":
 		case "github.com/paudley/e.NewWithVals[...]":
+		case "github.com/paudley/e.Newf[...]":
 		case "github.com/paudley/e.Wrap":
+		case "github.com/paudley/e.Wrapf":
 		case "github.com/paudley/e.WrapError[...]":
 		case "github.com/paudley/e.WrapErrorMsg[...]":
 		case "github.com/paudley/e.WrapErrorCtx[...]":
 		case "github.com/paudley/e.newPathElement":
-		case "github.com/paudley/e.WrapWithVals":
+		case "github.com/paudley/e.WrapWithVals[...]":
+		case "github.com/paudley/e.wrapWithVals[...]":
+		case "github.com/paudley/e.FullWrap[...]":
 		case "github.com/paudley/e.WrapErr":
+		case "github.com/paudley/e.Classify":
+		case "github.com/paudley/e.Retry":
+		case "github.com/paudley/e.retryFailed":
+		case "github.com/paudley/e.(*CircuitBreaker).Do":
 		case "blackcat.ca/fin.WithAppTx.func1.1":
 		case "blackcat.ca/fin.WithAppTx.func1":
 		case "blackcat.ca/app.(*EnhLogger).E":
@@ -82,7 +94,7 @@ func FilteredStack() ([]string, []CallFrame) {
 			file := frame.File
 			file = filestripRe.ReplaceAllString(file, ``)
 			ret = append(ret, fmt.Sprintf("%s@%s:%d", frame.Function, file, frame.Line))
-			ret2 = append(ret2, CallFrame{File: file, Line: frame.Line, Function: frame.Function})
+			ret2 = append(ret2, CallFrame{File: file, Line: frame.Line, Function: frame.Function, Path: frame.File})
 		}
 	}
 
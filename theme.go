@@ -0,0 +1,318 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	c "github.com/paudley/colorout"
+)
+
+// Style colors text with one of the colors of colorout (c.Red,
+// c.WhiteOnBlue, ...) or any other *color.Color.  The zero Style leaves
+// text unstyled.  A Style always colors its output, whatever the
+// environment: whether to color at all is decided by the Theme in use
+// (see ThemeFor).
+type Style struct {
+	col *color.Color
+}
+
+// NewStyle returns a Style coloring text with col.
+func NewStyle(col *color.Color) Style {
+	if col == nil {
+		return Style{}
+	}
+
+	// Copy col so that forcing color on does not change it for others.
+	forced := *col
+	forced.EnableColor()
+
+	return Style{col: &forced}
+}
+
+func (s Style) Sprint(a ...any) string {
+	if s.col == nil {
+		return fmt.Sprint(a...)
+	}
+
+	return s.col.Sprint(a...)
+}
+
+func (s Style) Sprintf(format string, a ...any) string {
+	return s.Sprint(fmt.Sprintf(format, a...))
+}
+
+// Theme sets the styles used by SummarizeConsole.
+type Theme struct {
+	Name string
+	// Banner is the error banner and the markers starting each line.
+	Banner Style
+	// Message is the error and path element messages.
+	Message Style
+	// Label is the labels ("id:", "context:") and notes such as
+	// truncation markers.
+	Label Style
+	// Field is context field values and a live context.
+	Field Style
+	// Warning is an ended context.
+	Warning Style
+	// Trace is IDs: the error ID and the trace context.
+	Trace Style
+	// Frame is the location of each path element but the last.
+	Frame Style
+	// LastFrame is the location of the last path element.
+	LastFrame Style
+	// Marker is the "--$" starting each value.
+	Marker Style
+	// Key is the label of keyed values without a renderer.
+	Key Style
+	// Added, Removed and Hunk style the lines of a Diff.
+	Added   Style
+	Removed Style
+	Hunk    Style
+	// Data is the hex dump of Bytes.
+	Data Style
+	// Badges style the labels of the built-in renderers, by value key.
+	// Keys without a badge use Key.
+	Badges map[string]Style
+	// Highlight keeps syntax highlighting and any other escapes in
+	// rendered values; without it values are shown as plain text.
+	Highlight bool
+}
+
+func (t Theme) badge(key string) Style {
+	if s, ok := t.Badges[key]; ok {
+		return s
+	}
+
+	return t.Key
+}
+
+func (t Theme) valueMarker() string {
+	return t.Banner.Sprint("-") + " " + t.Marker.Sprint("--$") + " "
+}
+
+var (
+	// ThemeDark is the default, for dark terminal backgrounds.
+	ThemeDark = Theme{
+		Name:      "dark",
+		Banner:    NewStyle(c.Red),
+		Message:   NewStyle(c.White),
+		Label:     NewStyle(c.Grey),
+		Field:     NewStyle(c.Green),
+		Warning:   NewStyle(c.Yellow),
+		Trace:     NewStyle(c.Cyan),
+		Frame:     NewStyle(c.Orange),
+		LastFrame: NewStyle(c.Yellow),
+		Marker:    NewStyle(c.Magenta),
+		Key:       NewStyle(c.WhiteOnMagenta),
+		Added:     NewStyle(c.Green),
+		Removed:   NewStyle(c.Red),
+		Hunk:      NewStyle(c.Cyan),
+		Data:      NewStyle(c.Cyan),
+		Badges: map[string]Style{
+			"db_error":   NewStyle(c.WhiteOnCyan),
+			"io_error":   NewStyle(c.WhiteOnRed),
+			"json":       NewStyle(c.WhiteOnGreen),
+			"sql":        NewStyle(c.WhiteOnBlue),
+			"validation": NewStyle(c.BlackOnYellow),
+		},
+		Highlight: true,
+	}
+
+	// ThemeLight avoids white and yellow text for light backgrounds.
+	ThemeLight = Theme{
+		Name:      "light",
+		Banner:    NewStyle(c.Red),
+		Message:   NewStyle(color.New(color.FgBlack, color.Bold)),
+		Label:     NewStyle(color.New(color.FgHiBlack)),
+		Field:     NewStyle(color.New(color.FgGreen)),
+		Warning:   NewStyle(color.New(color.FgMagenta, color.Bold)),
+		Trace:     NewStyle(color.New(color.FgCyan)),
+		Frame:     NewStyle(color.New(color.FgBlue)),
+		LastFrame: NewStyle(c.Blue),
+		Marker:    NewStyle(c.Magenta),
+		Key:       NewStyle(color.New(color.BgMagenta, color.Bold, color.FgHiWhite)),
+		Added:     NewStyle(color.New(color.FgGreen)),
+		Removed:   NewStyle(color.New(color.FgRed)),
+		Hunk:      NewStyle(color.New(color.FgBlue)),
+		Data:      NewStyle(color.New(color.FgBlue)),
+		Badges: map[string]Style{
+			"db_error":   NewStyle(color.New(color.BgCyan, color.Bold, color.FgHiWhite)),
+			"io_error":   NewStyle(color.New(color.BgRed, color.Bold, color.FgHiWhite)),
+			"json":       NewStyle(color.New(color.BgGreen, color.Bold, color.FgHiWhite)),
+			"sql":        NewStyle(color.New(color.BgBlue, color.Bold, color.FgHiWhite)),
+			"validation": NewStyle(color.New(color.BgYellow, color.FgBlack)),
+		},
+		Highlight: true,
+	}
+
+	// ThemeHighContrast uses only bright, bold colors and reverse video.
+	ThemeHighContrast = Theme{
+		Name:      "high-contrast",
+		Banner:    NewStyle(color.New(color.Bold, color.FgHiWhite, color.BgRed)),
+		Message:   NewStyle(color.New(color.Bold, color.FgHiWhite)),
+		Label:     NewStyle(color.New(color.Bold, color.FgHiWhite)),
+		Field:     NewStyle(color.New(color.Bold, color.FgHiGreen)),
+		Warning:   NewStyle(color.New(color.Bold, color.FgHiYellow)),
+		Trace:     NewStyle(color.New(color.Bold, color.FgHiCyan)),
+		Frame:     NewStyle(color.New(color.Bold, color.FgHiYellow)),
+		LastFrame: NewStyle(color.New(color.Bold, color.Underline, color.FgHiYellow)),
+		Marker:    NewStyle(color.New(color.Bold, color.FgHiMagenta)),
+		Key:       NewStyle(color.New(color.Bold, color.ReverseVideo)),
+		Added:     NewStyle(color.New(color.Bold, color.FgHiGreen)),
+		Removed:   NewStyle(color.New(color.Bold, color.FgHiRed)),
+		Hunk:      NewStyle(color.New(color.Bold, color.FgHiCyan)),
+		Data:      NewStyle(color.New(color.Bold, color.FgHiWhite)),
+		Highlight: true,
+	}
+
+	// ThemeMonochrome has no escapes at all.  It is used whenever color
+	// is disabled.
+	ThemeMonochrome = Theme{Name: "monochrome"}
+)
+
+// ColorMode controls when SummarizeConsole output is colored.
+type ColorMode int
+
+const (
+	// ColorAuto colors output only for terminals, and never when
+	// NO_COLOR is set or TERM is "dumb".
+	ColorAuto ColorMode = iota
+	// ColorAlways colors output regardless of where it goes.
+	ColorAlways
+	// ColorNever never colors output.
+	ColorNever
+)
+
+var (
+	themeMu       sync.RWMutex
+	theme         = ThemeDark
+	colorMode     = ColorAuto
+	terminalCheck = isTerminal
+)
+
+// SetTheme sets the theme used when output is colored.
+func SetTheme(t Theme) {
+	themeMu.Lock()
+	defer themeMu.Unlock()
+
+	theme = t
+}
+
+// CurrentTheme returns the theme set with SetTheme.
+func CurrentTheme() Theme {
+	themeMu.RLock()
+	defer themeMu.RUnlock()
+
+	return theme
+}
+
+// SetColorMode sets when output is colored.
+func SetColorMode(mode ColorMode) {
+	themeMu.Lock()
+	defer themeMu.Unlock()
+
+	colorMode = mode
+}
+
+// SetTerminalCheck replaces the function ColorEnabled and TerminalWidth
+// use to tell whether a writer is a terminal, for instance to test
+// color detection.  Pass nil to restore the default.
+func SetTerminalCheck(check func(w io.Writer) bool) {
+	themeMu.Lock()
+	defer themeMu.Unlock()
+
+	if check == nil {
+		check = isTerminal
+	}
+
+	terminalCheck = check
+}
+
+// ColorEnabled reports whether output written to w should be colored.
+func ColorEnabled(w io.Writer) bool {
+	themeMu.RLock()
+	mode := colorMode
+	check := terminalCheck
+	themeMu.RUnlock()
+
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	case ColorAuto:
+	}
+
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	return check(w)
+}
+
+// writesToTerminal reports whether w is a terminal, as decided by the
+// terminal check in use.
+func writesToTerminal(w io.Writer) bool {
+	themeMu.RLock()
+	check := terminalCheck
+	themeMu.RUnlock()
+
+	return check(w)
+}
+
+// isTerminal reports whether w is a file open on a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return false
+	}
+
+	fi, err := f.Stat()
+
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// summaryTheme is the theme for output not yet bound for a writer, such
+// as SummarizeConsole: the theme for stdout, where such output usually
+// ends up.
+func summaryTheme() Theme {
+	return ThemeFor(os.Stdout)
+}
+
+// ThemeFor returns the theme to use for output written to w: the
+// current theme if it should be colored, ThemeMonochrome if not.
+func ThemeFor(w io.Writer) Theme {
+	if ColorEnabled(w) {
+		return CurrentTheme()
+	}
+
+	return ThemeMonochrome
+}
+
+// stripANSI removes ANSI escape sequences from str.
+func stripANSI(str string) string {
+	if !strings.Contains(str, "\x1b") {
+		return str
+	}
+
+	var sb strings.Builder
+
+	for i := 0; i < len(str); i++ {
+		if skip := ansiSkip(str[i:]); skip > 0 {
+			i += skip - 1
+
+			continue
+		}
+
+		sb.WriteByte(str[i])
+	}
+
+	return sb.String()
+}
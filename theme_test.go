@@ -0,0 +1,113 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	c "github.com/paudley/colorout"
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func fThemed() e.Error {
+	return e.NewWithVals[e.DataError]("themed", func() e.Values {
+		return e.Values{e.V{"sql", "SELECT 1"}, e.V{"plain", 42}}
+	})
+}
+
+func TestTheme(t *testing.T) {
+	Convey("Verify console themes.", t, func() {
+		defer e.SetTheme(e.CurrentTheme())
+		defer e.SetColorMode(e.ColorAuto)
+		defer e.SetTerminalCheck(nil)
+
+		Convey("check monochrome output has no escapes", func() {
+			sum := fThemed().SummarizeConsoleTheme(e.ThemeMonochrome)
+			So(sum, ShouldNotContainSubstring, "\x1b")
+			So(sum, ShouldContainSubstring, "- --$  sql : SELECT 1\n")
+			So(sum, ShouldContainSubstring, "- --$  plain  => (int) 42\n")
+			So(fHTTPValues().SummarizeConsoleTheme(e.ThemeMonochrome), ShouldNotContainSubstring, "\x1b")
+		})
+		Convey("check built-in themes style the output", func() {
+			for _, th := range []e.Theme{e.ThemeDark, e.ThemeLight, e.ThemeHighContrast} {
+				sum := fThemed().SummarizeConsoleTheme(th)
+				So(sum, ShouldContainSubstring, th.Banner.Sprint("- ->"))
+				So(sum, ShouldContainSubstring, th.Message.Sprint("themed"))
+				So(fHTTPValues().SummarizeConsoleTheme(th), ShouldContainSubstring, th.Removed.Sprint("--- expected"))
+			}
+			So(fThemed().SummarizeConsoleTheme(e.ThemeDark), ShouldContainSubstring, e.NewStyle(c.WhiteOnBlue).Sprint(" sql "))
+			So(fThemed().SummarizeConsoleTheme(e.ThemeHighContrast), ShouldContainSubstring, e.ThemeHighContrast.Key.Sprint(" sql "))
+		})
+		Convey("check user defined themes", func() {
+			custom := e.ThemeMonochrome
+			custom.Name = "custom"
+			custom.Key = e.NewStyle(c.Blue)
+			sum := fThemed().SummarizeConsoleTheme(custom)
+			So(sum, ShouldContainSubstring, e.NewStyle(c.Blue).Sprint(" plain ")+" => (int) 42\n")
+			So(e.Style{}.Sprintf("%d", 7), ShouldEqual, "7")
+			So(e.NewStyle(c.Red).Sprint("x"), ShouldStartWith, "\x1b[")
+		})
+		Convey("check summaries are colored as for stdout", func() {
+			t.Setenv("NO_COLOR", "")
+			t.Setenv("TERM", "xterm")
+			e.SetTerminalCheck(func(w io.Writer) bool { return w == os.Stdout })
+			So(fThemed().SummarizeConsole(), ShouldContainSubstring, e.ThemeDark.Message.Sprint("themed"))
+			So(e.Bytes("hi").RenderConsole(), ShouldContainSubstring, "\x1b")
+
+			t.Setenv("NO_COLOR", "1")
+			So(fThemed().SummarizeConsole(), ShouldNotContainSubstring, "\x1b")
+			So(e.Bytes("hi").RenderConsole(), ShouldNotContainSubstring, "\x1b")
+			t.Setenv("NO_COLOR", "")
+			t.Setenv("TERM", "dumb")
+			So(fThemed().SummarizeConsole(), ShouldNotContainSubstring, "\x1b")
+			t.Setenv("TERM", "xterm")
+
+			e.SetTerminalCheck(func(io.Writer) bool { return false })
+			So(fThemed().SummarizeConsole(), ShouldNotContainSubstring, "\x1b")
+			e.SetColorMode(e.ColorAlways)
+			So(fThemed().SummarizeConsole(), ShouldContainSubstring, e.ThemeDark.Message.Sprint("themed"))
+			e.SetColorMode(e.ColorNever)
+			e.SetTerminalCheck(func(io.Writer) bool { return true })
+			So(fThemed().SummarizeConsole(), ShouldNotContainSubstring, "\x1b")
+		})
+		Convey("check color detection", func() {
+			t.Setenv("NO_COLOR", "")
+			t.Setenv("TERM", "xterm")
+			var buf bytes.Buffer
+			So(e.ColorEnabled(&buf), ShouldBeFalse)
+			So(e.ThemeFor(&buf).Name, ShouldEqual, "monochrome")
+
+			e.SetTerminalCheck(func(w io.Writer) bool { return w == &buf })
+			So(e.ColorEnabled(&buf), ShouldBeTrue)
+			So(e.ColorEnabled(io.Discard), ShouldBeFalse)
+			So(fThemed().WriteConsole(&buf), ShouldBeNil)
+			So(buf.String(), ShouldContainSubstring, e.ThemeDark.Message.Sprint("themed"))
+
+			t.Setenv("TERM", "dumb")
+			So(e.ColorEnabled(&buf), ShouldBeFalse)
+			t.Setenv("TERM", "xterm")
+			t.Setenv("NO_COLOR", "1")
+			So(e.ColorEnabled(&buf), ShouldBeFalse)
+			So(e.ThemeFor(&buf).Name, ShouldEqual, "monochrome")
+
+			e.SetColorMode(e.ColorAlways)
+			e.SetTheme(e.ThemeLight)
+			So(e.ThemeFor(io.Discard).Name, ShouldEqual, "light")
+
+			e.SetColorMode(e.ColorNever)
+			e.SetTerminalCheck(func(io.Writer) bool { return true })
+			So(e.ColorEnabled(&buf), ShouldBeFalse)
+		})
+		Convey("check piped dedup output is plain", func() {
+			var buf bytes.Buffer
+			d := e.NewDeduper(e.DedupConfig{W: &buf})
+			d.Log(fThemed())
+			So(strings.Contains(buf.String(), "\x1b"), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,182 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+
+package e
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TraceparentHeader is the W3C trace context header.
+const TraceparentHeader = "traceparent"
+
+const (
+	traceIDLen     = 32
+	spanIDLen      = 16
+	traceparentLen = 55
+	flagSampled    = 0x01
+)
+
+// TraceContext is the W3C trace context (trace and parent span IDs) an
+// error was created under.
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+	Flags   byte
+}
+
+// Valid reports whether tc has non-zero trace and span IDs.
+func (tc TraceContext) Valid() bool {
+	return validTraceHex(tc.TraceID, traceIDLen) && validTraceHex(tc.SpanID, spanIDLen)
+}
+
+// Sampled reports whether the sampled flag is set.
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&flagSampled != 0
+}
+
+// Traceparent formats tc as a version 00 traceparent header value.
+func (tc TraceContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", tc.TraceID, tc.SpanID, tc.Flags)
+}
+
+func (tc TraceContext) String() string {
+	return tc.Traceparent()
+}
+
+// JSON returns the trace context for structured output.
+func (tc TraceContext) JSON() map[string]any {
+	return map[string]any{
+		"TraceID":     tc.TraceID,
+		"SpanID":      tc.SpanID,
+		"Sampled":     tc.Sampled(),
+		"Traceparent": tc.Traceparent(),
+	}
+}
+
+func isLowerHex(str string) bool {
+	for _, r := range str {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+
+	return str != ""
+}
+
+// validTraceHex reports whether str is a non-zero lowercase hex ID.
+func validTraceHex(str string, length int) bool {
+	return len(str) == length && isLowerHex(str) && strings.Trim(str, "0") != ""
+}
+
+// ErrMalformedTraceparent is returned by ParseTraceparent for header
+// values it cannot use.  It is a plain error so that junk headers from
+// clients cost nothing; wrap it if an Error is wanted.
+var ErrMalformedTraceparent = errors.New("malformed traceparent")
+
+// ParseTraceparent parses a W3C traceparent header value.  Headers from
+// future versions are accepted as long as their version 00 prefix is
+// well formed.
+func ParseTraceparent(header string) (TraceContext, error) {
+	header = strings.TrimSpace(header)
+	parts := strings.Split(header, "-")
+
+	if len(header) < traceparentLen || len(parts) < 4 {
+		return TraceContext{}, ErrMalformedTraceparent
+	}
+
+	version := parts[0]
+	if len(version) != 2 || !isLowerHex(version) || version == "ff" || (version == "00" && len(header) != traceparentLen) {
+		return TraceContext{}, ErrMalformedTraceparent
+	}
+
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil || len(parts[3]) != 2 || !isLowerHex(parts[3]) {
+		return TraceContext{}, ErrMalformedTraceparent
+	}
+
+	tc := TraceContext{TraceID: parts[1], SpanID: parts[2], Flags: byte(flags)}
+	if !tc.Valid() {
+		return TraceContext{}, ErrMalformedTraceparent
+	}
+
+	return tc, nil
+}
+
+// TraceExtractor reads the active trace from a context, for instance
+// from an OpenTelemetry span, returning false if there is none.
+type TraceExtractor func(ctx context.Context) (TraceContext, bool)
+
+type traceKey struct{}
+
+var (
+	traceMu         sync.RWMutex
+	traceExtractors []TraceExtractor
+)
+
+// AddTraceExtractor adds an extractor consulted by TraceFromContext,
+// after any trace set with ContextWithTrace.  This keeps tracing
+// libraries out of this package: adapt them with a small extractor.
+func AddTraceExtractor(extractor TraceExtractor) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+
+	traceExtractors = append(traceExtractors, extractor)
+}
+
+// ContextWithTrace returns a copy of ctx carrying tc.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceKey{}, tc)
+}
+
+// TraceFromContext returns the trace context carried by ctx.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	if ctx == nil {
+		return TraceContext{}, false
+	}
+
+	if tc, ok := ctx.Value(traceKey{}).(TraceContext); ok && tc.Valid() {
+		return tc, true
+	}
+
+	traceMu.RLock()
+	defer traceMu.RUnlock()
+
+	for _, extractor := range traceExtractors {
+		if tc, ok := extractor(ctx); ok && tc.Valid() {
+			return tc, true
+		}
+	}
+
+	return TraceContext{}, false
+}
+
+// TraceMiddleware parses the traceparent header of incoming requests
+// and stores the trace in the request context, so that errors created
+// with that context record it.  Requests with a missing or malformed
+// header are passed through untouched.
+func TraceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header := r.Header.Get(TraceparentHeader); header != "" {
+			if tc, err := ParseTraceparent(header); err == nil {
+				r = r.WithContext(ContextWithTrace(r.Context(), tc))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Trace returns the trace context recorded when the error was created.
+func (e Error) Trace() (TraceContext, bool) {
+	if e == nil || e.trace == nil {
+		return TraceContext{}, false
+	}
+
+	return *e.trace, true
+}
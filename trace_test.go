@@ -0,0 +1,87 @@
+// Copyright (C) 2024 by Blackcat Informatics® Inc.
+// nolint
+package e_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/paudley/e"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+type otelSpanKey struct{}
+
+func TestTraceContext(t *testing.T) {
+	Convey("Verify that W3C trace context is recorded on errors.", t, func() {
+		Convey("check traceparent parsing", func() {
+			tc, err := e.ParseTraceparent(testTraceparent)
+			So(err, ShouldBeNil)
+			So(tc.TraceID, ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+			So(tc.SpanID, ShouldEqual, "00f067aa0ba902b7")
+			So(tc.Sampled(), ShouldBeTrue)
+			So(tc.Traceparent(), ShouldEqual, testTraceparent)
+			_, err = e.ParseTraceparent("cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future")
+			So(err, ShouldBeNil)
+			for _, bad := range []string{
+				"",
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+				"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+				"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			} {
+				_, err = e.ParseTraceparent(bad)
+				So(errors.Is(err, e.ErrMalformedTraceparent), ShouldBeTrue)
+			}
+		})
+		Convey("check the middleware records the trace on errors", func() {
+			var got e.Error
+			h := e.TraceMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = e.NewWithContext[e.APIError](r.Context(), "handler failed")
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Traceparent", testTraceparent)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			tc, ok := got.Trace()
+			So(ok, ShouldBeTrue)
+			So(tc.SpanID, ShouldEqual, "00f067aa0ba902b7")
+			So(got.SummarizeConsole(), ShouldContainSubstring, testTraceparent)
+			So(got.JSON()["Trace"].(map[string]any)["TraceID"], ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+		})
+		Convey("check junk headers create no errors", func() {
+			created := 0
+			remove := e.AddHook(func(e.HookEvent, e.Error, e.PathElement) { created++ })
+			defer remove()
+			traced := true
+			h := e.TraceMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, traced = e.TraceFromContext(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Traceparent", "junk")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			So(traced, ShouldBeFalse)
+			So(created, ShouldEqual, 0)
+		})
+		Convey("check pluggable extractors", func() {
+			e.AddTraceExtractor(func(ctx context.Context) (e.TraceContext, bool) {
+				tc, ok := ctx.Value(otelSpanKey{}).(e.TraceContext)
+				return tc, ok
+			})
+			ctx := context.WithValue(context.Background(), otelSpanKey{}, e.TraceContext{TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"})
+			err := e.NewWithContext[e.APIError](ctx, "oops")
+			tc, ok := err.Trace()
+			So(ok, ShouldBeTrue)
+			So(tc.Sampled(), ShouldBeFalse)
+			_, ok = e.NewWithContext[e.APIError](context.Background(), "oops").Trace()
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
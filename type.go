@@ -6,7 +6,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -30,10 +32,16 @@ type PathElement struct {
 	FileName   string
 	LineNumber int
 	FuncName   string
-	Msg        string
-	ValFunc    ValueFunc
-	values     Values
-	valuesP    bool
+	// FilePath is the full path of FileName, used to show source.
+	FilePath string
+	Msg      string
+	// Template is the format string Msg was rendered from by Newf or
+	// Wrapf, or empty.
+	Template string
+	ValFunc  ValueFunc
+	values   Values
+	valuesP  bool
+	args     []any
 }
 
 type ErrorClass interface {
@@ -46,12 +54,19 @@ type ErrorClass interface {
 // around but is opaque by design to calling code.
 type errorData struct {
 	createdAt   time.Time
+	id          atomic.Pointer[string]
 	originerror error
-	// We make a copy of the ctx here, not to use in a context sense but so we can dump it as part of the error.
+	// The ctx is only kept (see SetRetainContext) so that it can be dumped as part of the
+	// error; by default only the registered fields are extracted from it.
 	//nolint: containedctx
 	originContext  context.Context
 	originContextP bool
+	contextFields  []ContextField
+	ctxState       *ContextState
+	trace          *TraceContext
+	ambientSeen    map[*ambientLayer]bool
 	class          ErrorClass
+	public         *PublicInfo
 	path           []PathElement
 }
 
@@ -60,14 +75,18 @@ type Error = *errorData
 
 // Resolve the set of values for this path element.
 func (pe PathElement) Values() (vals Values) {
-	if pe.ValFunc == nil {
-		return Values{}
-	}
-
 	if pe.valuesP {
 		return pe.values
 	}
 
+	// pe.values holds values captured at creation (e.g. ambient context
+	// annotations); copy so that appending never shares storage.
+	vals = append(Values{}, pe.values...)
+
+	if pe.ValFunc == nil {
+		return vals
+	}
+
 	defer func() {
 		if panicErr := recover(); panicErr != nil {
 			// panic'ed in ValFunc.  Not a good sign...
@@ -80,11 +99,10 @@ func (pe PathElement) Values() (vals Values) {
 				msg = errV.Error()
 			}
 
-			vals = Values{V{"panic", "PANIC in ValFunc: " + msg}}
+			vals = append(append(Values{}, pe.values...), V{"panic", "PANIC in ValFunc: " + msg})
 		}
 	}()
 
-	vals = pe.values
 	vals = append(vals, pe.ValFunc()...)
 
 	return vals
@@ -97,11 +115,14 @@ func newPathElement(msg string) PathElement {
 		FileName:   pc.File,
 		LineNumber: pc.Line,
 		FuncName:   pc.Function,
+		FilePath:   pc.Path,
 		Msg:        msg,
 	}
 }
 
-func New[T ErrorClass](msg string) Error {
+// newError builds an error without announcing it; the public
+// constructors call created once the error is complete.
+func newError[T ErrorClass](msg string) Error {
 	var ec T
 
 	return &errorData{
@@ -111,24 +132,65 @@ func New[T ErrorClass](msg string) Error {
 	}
 }
 
+// clone returns a copy of e that later changes to e, such as Wrap or
+// AddValue, do not reach.  The ID is kept, so both are reported as the
+// same error.
+func (e Error) clone() Error {
+	id := e.ID()
+	cp := &errorData{
+		createdAt:      e.createdAt,
+		originerror:    e.originerror,
+		originContext:  e.originContext,
+		originContextP: e.originContextP,
+		contextFields:  append([]ContextField(nil), e.contextFields...),
+		ctxState:       e.ctxState,
+		trace:          e.trace,
+		class:          e.class,
+		public:         e.public,
+		path:           append([]PathElement(nil), e.path...),
+	}
+	cp.id.Store(&id)
+
+	if e.ambientSeen != nil {
+		cp.ambientSeen = make(map[*ambientLayer]bool, len(e.ambientSeen))
+		for l, seen := range e.ambientSeen {
+			cp.ambientSeen[l] = seen
+		}
+	}
+
+	return cp
+}
+
+func New[T ErrorClass](msg string) Error {
+	e := newError[T](msg)
+	e.created()
+
+	return e
+}
+
 func NewWithContext[T ErrorClass](ctx context.Context, msg string) Error {
-	e := New[T](msg)
-	e.originContext = ctx
-	e.originContextP = true
+	e := newError[T](msg)
+	e.attachContext(ctx)
+	e.attachAmbient(ctx)
+	e.created()
 
 	return e
 }
 
 func NewWithVals[T ErrorClass](msg string, valFunc ValueFunc) Error {
-	e := New[T](msg)
+	e := newError[T](msg)
 	e.path[0].ValFunc = valFunc
+	e.created()
 
 	return e
 }
 
 func Full[T ErrorClass](ctx context.Context, msg string, valFunc ValueFunc) Error {
-	e := NewWithContext[T](ctx, msg)
+	e := newError[T](msg)
+	e.attachContext(ctx)
+	e.attachAmbient(ctx)
 	e.path[0].ValFunc = valFunc
+	e.created()
 
 	return e
 }
@@ -139,17 +201,16 @@ func Wrap(errorToWrap Error, msg string) Error {
 	}
 
 	errorToWrap.path = append(errorToWrap.path, newPathElement(msg))
+	errorToWrap.wrapped()
 
 	return errorToWrap
 }
 
-func WrapError[T ErrorClass](err error) Error {
-	if err == nil {
-		return New[NoError]("no error")
-	}
-
-	eData := New[T](err.Error())
+// wrapError wraps a non-nil go error without announcing it.
+func wrapError[T ErrorClass](err error) Error {
+	eData := newError[T](err.Error())
 	eData.originerror = err
+	eData.classify()
 
 	eData.path[0].ValFunc = func() Values {
 		return Values{
@@ -161,19 +222,28 @@ func WrapError[T ErrorClass](err error) Error {
 	return eData
 }
 
-func WrapErrorMsg[T ErrorClass](err error, msg string) Error {
+// WrapError wraps a go error.  If T is UnknownError the class is chosen
+// by the classification rules (see ClassOf).
+func WrapError[T ErrorClass](err error) Error {
 	if err == nil {
 		return New[NoError]("no error")
 	}
 
-	eData := New[T](err.Error())
-	eData.path[0].ValFunc = func() Values {
-		return Values{
-			"wrapped_error",
-			V{K: "err", I: err},
-		}
+	eData := wrapError[T](err)
+	eData.created()
+
+	return eData
+}
+
+func WrapErrorMsg[T ErrorClass](err error, msg string) Error {
+	if err == nil {
+		return New[NoError]("no error")
 	}
+
+	eData := wrapError[T](err)
+	eData.created()
 	eData.path = append(eData.path, newPathElement(msg))
+	eData.wrapped()
 
 	return eData
 }
@@ -181,6 +251,10 @@ func WrapErrorMsg[T ErrorClass](err error, msg string) Error {
 func (e Error) AddValues(valFunc ValueFunc) Error {
 	valFuncOrig := e.path[len(e.path)-1].ValFunc
 	e.path[len(e.path)-1].ValFunc = func() Values {
+		if valFuncOrig == nil {
+			return valFunc()
+		}
+
 		return append(valFuncOrig(), valFunc()...)
 	}
 
@@ -188,25 +262,28 @@ func (e Error) AddValues(valFunc ValueFunc) Error {
 }
 
 func (e Error) AddValue(key string, val any) Error {
-	valFuncOrig := e.path[len(e.path)-1].ValFunc
-	e.path[len(e.path)-1].ValFunc = func() Values {
-		return append(valFuncOrig(), func() Values { return Values{V{K: key, I: val}} })
-	}
-
-	return e
+	return e.AddValues(func() Values { return Values{V{K: key, I: val}} })
 }
 
 func WrapErrorCtx[T ErrorClass](ctx context.Context, err error) Error {
-	e := WrapError[T](err)
-	e.originContext = ctx
-	e.originContextP = true
+	var e Error
+	if err == nil {
+		e = newError[NoError]("no error")
+	} else {
+		e = wrapError[T](err)
+	}
+
+	e.attachContext(ctx)
+	e.attachAmbient(ctx)
+	e.created()
 
 	return e
 }
 
-func WrapWithVals[T ErrorClass](errorToWrap Error, msg string, valFunc ValueFunc) Error {
+// wrapWithVals adds a path element with values without announcing it.
+func wrapWithVals[T ErrorClass](errorToWrap Error, msg string, valFunc ValueFunc) Error {
 	if errorToWrap == nil {
-		return New[T](msg)
+		return newError[T](msg)
 	}
 
 	errorToWrap.path = append(errorToWrap.path, newPathElement(msg))
@@ -215,31 +292,134 @@ func WrapWithVals[T ErrorClass](errorToWrap Error, msg string, valFunc ValueFunc
 	return errorToWrap
 }
 
+func WrapWithVals[T ErrorClass](errorToWrap Error, msg string, valFunc ValueFunc) Error {
+	eData := wrapWithVals[T](errorToWrap, msg, valFunc)
+	if errorToWrap == nil {
+		eData.created()
+	} else {
+		eData.wrapped()
+	}
+
+	return eData
+}
+
 // FullWrap wraps an Error, adds a message and values and possibly updates missing context information.
 func FullWrap[T ErrorClass](ctx context.Context, errorToWrap Error, msg string, valFunc ValueFunc) Error {
-	if !errorToWrap.originContextP {
-		errorToWrap.originContext = ctx
-		errorToWrap.originContextP = true
+	eData := wrapWithVals[T](errorToWrap, msg, valFunc)
+	if !eData.originContextP {
+		eData.attachContext(ctx)
+	}
+
+	eData.attachAmbient(ctx)
+
+	if errorToWrap == nil {
+		eData.created()
+	} else {
+		eData.wrapped()
+	}
+
+	return eData
+}
+
+// format renders Msg from a format and its args, keeping the template
+// and recording the args as values "arg1", "arg2", ... (numbered as in
+// %[1]v).  Any error wrapped with %w is returned, joined with
+// errors.Join if there are several.
+func (pe *PathElement) format(format string, args []any) error {
+	rendered := fmt.Errorf(format, args...) // nolint: goerr113
+
+	pe.Msg = rendered.Error()
+	pe.Template = format
+	pe.args = args
+
+	for i, arg := range args {
+		pe.values = append(pe.values, V{K: "arg" + strconv.Itoa(i+1), I: arg})
+	}
+
+	switch wrapped := rendered.(type) { // nolint: errorlint
+	case interface{ Unwrap() []error }:
+		return errors.Join(wrapped.Unwrap()...)
+	case interface{ Unwrap() error }:
+		return wrapped.Unwrap()
 	}
 
-	return WrapWithVals[T](errorToWrap, msg, valFunc)
+	return nil
+}
+
+// Args returns the arguments the message was formatted with by Newf or
+// Wrapf.
+func (pe PathElement) Args() []any {
+	return pe.args
+}
+
+// Newf creates an error with a formatted message.  The format is kept
+// as the path element's Template, so errors differing only in their
+// args share a fingerprint, and the args are recorded as values.  An
+// error given for a %w verb becomes the wrapped origin error.
+func Newf[T ErrorClass](format string, args ...any) Error {
+	eData := newError[T]("")
+
+	if wrapped := eData.path[0].format(format, args); wrapped != nil {
+		eData.originerror = wrapped
+		eData.classify()
+	}
+
+	eData.created()
+
+	return eData
+}
+
+// Wrapf is Wrap with a formatted message, recorded as with Newf.  An
+// error given for a %w verb becomes the origin error, joined with
+// errors.Join to the origin error if there already is one.
+func Wrapf(errorToWrap Error, format string, args ...any) Error {
+	if errorToWrap == nil {
+		eData := newError[UnknownError]("")
+		if wrapped := eData.path[0].format(format, args); wrapped != nil {
+			eData.originerror = wrapped
+			eData.classify()
+		}
+
+		eData.created()
+
+		return eData
+	}
+
+	pe := newPathElement("")
+	if wrapped := pe.format(format, args); wrapped != nil {
+		if errorToWrap.originerror != nil {
+			wrapped = errors.Join(errorToWrap.originerror, wrapped)
+		}
+
+		errorToWrap.originerror = wrapped
+		errorToWrap.classify()
+	}
+
+	errorToWrap.path = append(errorToWrap.path, pe)
+	errorToWrap.wrapped()
+
+	return errorToWrap
 }
 
 func (e Error) Path() []PathElement {
 	return e.path
 }
 
+// OriginContext returns the context the error was created with, if it
+// was retained (see SetRetainContext).
 func (e Error) OriginContext() *context.Context {
-	if e.originContextP {
+	if e.originContextP && e.originContext != nil {
 		return &e.originContext
 	}
 
 	return nil
 }
 
+// OriginContextString formats the fields extracted from the origin
+// context (see RegisterContextKey) as key=value pairs.
 func (e Error) OriginContextString() string {
 	if e.originContextP {
-		return fmt.Sprintf("%v", e.originContext)
+		return contextFieldsString(preparedContextFields(outputConsole, e.contextFields, &Truncation{}))
 	}
 
 	return ""
@@ -260,6 +440,10 @@ func (e Error) Error() string {
 		msgs = append(msgs, e.path[i].Msg)
 	}
 
+	if idInError.Load() {
+		return strings.Join(msgs, "; ") + " [id: " + e.ID() + "]"
+	}
+
 	return strings.Join(msgs, "; ")
 }
 
@@ -275,6 +459,14 @@ func (e Error) Class() ErrorClass {
 	return e.class
 }
 
+// classify replaces an UnknownError class with one chosen from the
+// wrapped go error.
+func (e Error) classify() {
+	if e.originerror != nil && e.class.Number() == (UnknownError{}).Number() {
+		e.class = ClassOf(e.originerror)
+	}
+}
+
 func SetClass[T ErrorClass](errorToUpdate Error) {
 	if errorToUpdate.class.Number() != 1 {
 		return
@@ -282,6 +474,8 @@ func SetClass[T ErrorClass](errorToUpdate Error) {
 
 	var ec T
 	errorToUpdate.class = ec
+
+	runHooks(HookSetClass, errorToUpdate, errorToUpdate.path[len(errorToUpdate.path)-1])
 }
 
 func (e Error) Is(target error) bool {